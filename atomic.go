@@ -0,0 +1,227 @@
+package basicfile
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+type (
+	// AtomicFile is a BasicFile whose contents replace
+	// the named file all at once.
+	//
+	// Writes go to a temporary file in the same directory
+	// as the target. Commit makes them durable and renames
+	// the temporary file over the target, so readers see
+	// either the old or the new contents, never a partial
+	// write. Abort discards them.
+	AtomicFile interface {
+		BasicFile
+		io.Writer
+		io.WriterAt
+		io.StringWriter
+
+		// Commit syncs the temporary file, renames it
+		// over the target and syncs the directory.
+		Commit() error
+
+		// Flush syncs the temporary file to stable
+		// storage. Writes continue after the data
+		// already written.
+		Flush() error
+
+		// Abort closes and removes the temporary file,
+		// leaving the target unchanged.
+		Abort() error
+	}
+
+	atomicFile struct {
+		basicFile // the temporary file
+		target    string
+		perm      os.FileMode
+
+		amu  sync.Mutex // guards done
+		done bool       // Commit or Abort has been called
+	}
+)
+
+// ErrNotDurable is matched by the error from Commit when
+// the temporary file was renamed over the target but the
+// directory could not be synced: the target has the new
+// contents, but the rename may not survive a crash.
+var ErrNotDurable = NewGoFileError("", "", errors.New("committed but not durable"))
+
+// NewAtomicFile returns an AtomicFile that replaces the
+// named file when it is committed. On Commit, the file
+// mode is set to perm.
+//
+// If there is an error, it will be of type *GoFileError.
+func NewAtomicFile(name string, perm os.FileMode) (AtomicFile, error) {
+	dir, base := filepath.Split(name)
+	if dir == "" {
+		dir = "."
+	}
+
+	tmp, err := os.CreateTemp(dir, "."+base+".tmp-*")
+	if err != nil {
+		return nil, Err(NewGoFileError("gofile.NewAtomicFile", name, err))
+	}
+
+	f := &atomicFile{
+		target: name,
+		perm:   perm,
+	}
+	f.basicFile.init(tmp.Name())
+	f.attachLocked(tmp, true)
+	return f, nil
+}
+
+// WriteFileAtomic writes data to the named file, creating
+// it if necessary, so that the file contains either its
+// old contents or all of data, even if the process or
+// system crashes. The file mode is set to perm.
+//
+// If there is an error, it will be of type *GoFileError.
+func WriteFileAtomic(name string, data []byte, perm os.FileMode) error {
+	f, err := NewAtomicFile(name, perm)
+	if err != nil {
+		return err
+	}
+
+	_, err = f.Write(data)
+	if err != nil {
+		f.Abort()
+		return Err(NewGoFileError("gofile.WriteFileAtomic", name, err))
+	}
+	return f.Commit()
+}
+
+// Name returns the base name of the target file.
+func (f *atomicFile) Name() string {
+	return filepath.Base(f.target)
+}
+
+// WriteString writes the contents of s to the
+// temporary file.
+func (f *atomicFile) WriteString(s string) (n int, err error) {
+	return f.Write([]byte(s))
+}
+
+// Flush syncs the temporary file to stable storage.
+// Unlike BasicFile.Flush, it does not close the file,
+// so later writes continue after the data already
+// written. Flush after Commit or Abort does nothing.
+func (f *atomicFile) Flush() error {
+	f.amu.Lock()
+	defer f.amu.Unlock()
+	if f.done {
+		return nil
+	}
+	if err := f.Sync(); err != nil {
+		return Err(NewGoFileError("gofile.Flush", f.providedName, err))
+	}
+	return nil
+}
+
+// Commit syncs the temporary file, sets its mode, renames
+// it over the target and syncs the parent directory so
+// that the rename itself is durable.
+//
+// If any step before the rename fails, the temporary file
+// is removed and the target is left unchanged. If only
+// the directory sync fails, the target already has the
+// new contents and the error matches ErrNotDurable.
+func (f *atomicFile) Commit() error {
+	const op = "gofile.Commit"
+	f.amu.Lock()
+	defer f.amu.Unlock()
+	if f.done {
+		return NewGoFileError(op, f.target, ErrClosed)
+	}
+	f.done = true
+
+	if err := f.commit(); err != nil {
+		f.discard()
+		return Err(NewGoFileError(op, f.target, err))
+	}
+	if err := syncDir(filepath.Dir(f.target)); err != nil {
+		return Err(&GoFileError{
+			Op:   prependGoFilePrefix(op),
+			Path: f.target,
+			Err:  fmt.Errorf("%w: %v", ErrNotDurable, err),
+		})
+	}
+	return nil
+}
+
+// Abort closes and removes the temporary file. The target
+// is left unchanged. Abort after Commit does nothing.
+func (f *atomicFile) Abort() error {
+	f.amu.Lock()
+	defer f.amu.Unlock()
+	if f.done {
+		return nil
+	}
+	f.done = true
+
+	err := f.discard()
+	if err != nil {
+		return Err(NewGoFileError("gofile.Abort", f.target, err))
+	}
+	return nil
+}
+
+// Close aborts the file unless it has been committed.
+func (f *atomicFile) Close() error {
+	return f.Abort()
+}
+
+// commit syncs the temporary file, sets its mode and
+// renames it over the target.
+func (f *atomicFile) commit() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	ff, err := f.openLocked()
+	if err != nil {
+		return err
+	}
+	if err := ff.Sync(); err != nil {
+		return err
+	}
+	if err := ff.Chmod(f.perm); err != nil {
+		return err
+	}
+	if err := f.closeLocked(); err != nil {
+		return err
+	}
+	return os.Rename(f.providedName, f.target)
+}
+
+// discard closes and removes the temporary file.
+func (f *atomicFile) discard() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.closeLocked()
+	err := os.Remove(f.providedName)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+// syncDir commits the directory entries of dir to
+// stable storage. It is a variable so that tests can
+// make it fail.
+var syncDir = func(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
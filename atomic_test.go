@@ -0,0 +1,76 @@
+package basicfile
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteFileAtomic(t *testing.T) {
+	dir := t.TempDir()
+	name := filepath.Join(dir, "config.json")
+	if err := os.WriteFile(name, []byte("old"), NormalMode); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := WriteFileAtomic(name, []byte("new"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	got, _ := os.ReadFile(name)
+	if string(got) != "new" {
+		t.Errorf("contents = %q, want %q", got, "new")
+	}
+	if fi, _ := os.Stat(name); fi.Mode().Perm() != 0600 {
+		t.Errorf("mode = %v, want %v", fi.Mode().Perm(), os.FileMode(0600))
+	}
+
+	f, err := NewAtomicFile(name, NormalMode)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString("discarded")
+	if err := f.Abort(); err != nil {
+		t.Fatal(err)
+	}
+	got, _ = os.ReadFile(name)
+	if string(got) != "new" {
+		t.Errorf("contents after Abort() = %q, want %q", got, "new")
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("directory has %d entries after Abort(), want 1", len(entries))
+	}
+}
+
+func TestAtomicFileFlush(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "data.txt")
+	f, err := NewAtomicFile(name, NormalMode)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString("first ")
+	if err := f.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString("second")
+	if err := f.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := os.ReadFile(name); string(got) != "first second" {
+		t.Errorf("contents = %q, want %q", got, "first second")
+	}
+}
+
+func TestAtomicFileNotDurable(t *testing.T) {
+	defer func(fn func(string) error) { syncDir = fn }(syncDir)
+	syncDir = func(string) error { return ErrShortWrite }
+
+	name := filepath.Join(t.TempDir(), "data.txt")
+	err := WriteFileAtomic(name, []byte("new"), NormalMode)
+	if !errors.Is(err, ErrNotDurable) {
+		t.Errorf("WriteFileAtomic() = %v, want ErrNotDurable", err)
+	}
+	if got, _ := os.ReadFile(name); string(got) != "new" {
+		t.Errorf("contents = %q, want %q", got, "new")
+	}
+}
@@ -5,11 +5,15 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"sync"
 	"time"
 )
 
-func NewBasicFile(filename string) (BasicFile, error) {
-	return &basicFile{providedName: filename}, nil
+// NewBasicFile returns a BasicFile for the named file
+// without opening it. The file is opened, as configured
+// by opts, when it is first used.
+func NewBasicFile(filename string, opts ...Option) (BasicFile, error) {
+	return OpenFile(filename, append(opts, withLazy())...)
 }
 
 func newFileWithErr(providedName string) (BasicFile, error) {
@@ -49,66 +53,51 @@ func newFile(providedName string) BasicFile {
 // Open opens the named file for reading as an in memory object.
 // If successful, methods on the returned file can be used for
 // reading; the associated file descriptor has mode O_RDONLY.
-// If there is an error, it will be of type *os.PathError.
+// If there is an error, it will be of type *GoFileError.
 func Open(name string) (BasicFile, error) {
-	f, err := os.Open(name)
-	if Err(err) != nil {
-		return nil, NewGoFileError("gofile.Open", name, err)
-	}
-
-	return NewBasicFile(f.Name())
+	return OpenFile(name, WithReadOnly())
 }
 
 // Create creates or truncates the named file and returns an
-// opened file as io.ReadWriteCloser.
+// opened BasicFile.
 //
 // If the file already exists, it is truncated. If the file
 // does not exist, it is created with mode 0644 (before umask).
 // If successful, methods on the returned File can be used
 // for I/O; the associated file descriptor has mode O_RDWR.
 //
-// If there is an error, it will be of type *PathError.
+// If there is an error, it will be of type *GoFileError.
 func Create(name string) (BasicFile, error) {
-	b := &basicFile{providedName: name}
-
-	err := b.Create()
-	if err != nil {
-		return nil, err
-	}
-
-	defer b.timeStamp()
-
-	// standard library: OpenFile is the generalized open call; most users
-	// will use Open or Create instead. It opens the named file with specified
-	// flag (O_RDONLY etc.). If the file does not exist, and the O_CREATE flag
-	// is passed, it is created with mode perm (before umask). If successful,
-	// methods on the returned File can be used for I/O. If there is an error,
-	// it will be of type *PathError.
-	f, err := os.OpenFile(name, os.O_RDWR|os.O_CREATE|os.O_TRUNC, NormalMode)
-	if err != nil {
-		return nil, Err(NewGoFileError("gofile.Create", name, err))
-	}
-
-	b.File = f
-
-	return b, nil
+	return OpenFile(name, WithFlags(os.O_RDWR|os.O_CREATE|os.O_TRUNC))
 }
 
 // CreateSafe creates the named file and returns an
-// opened file as io.ReadWriteCloser.
+// opened BasicFile.
 //
 // If the file already exists, an error is returned. If the file
 // does not exist, it is created with mode 0644 (before umask).
 // If successful, methods on the returned File can be used
 // for I/O; the associated file descriptor has mode O_RDWR.
 //
-// If there is an error, it will be of type *PathError.
-func CreateSafe(name string) (io.ReadWriteCloser, error) {
-	f, err := os.OpenFile(name, os.O_RDWR|os.O_CREATE|os.O_APPEND, NormalMode)
-	if err != nil {
-		return nil, NewGoFileError("gofile.CreateSafe", name, err)
-	}
-	return f, nil
+// The existence check and creation are a single atomic
+// operation (O_CREATE|O_EXCL), so CreateSafe is suitable
+// for lock files and PID files. O_EXCL also refuses to
+// create the file through a symbolic link in the final
+// path element, even one whose target does not exist.
+//
+// If there is an error, it will be of type *GoFileError.
+// If the file exists, the error matches ErrExist.
+func CreateSafe(name string) (BasicFile, error) {
+	return OpenFile(name, WithFlags(os.O_RDWR|os.O_CREATE|os.O_EXCL))
+}
+
+// CreateSafeNoFollow is CreateSafe with WithNoFollow.
+// Because O_CREATE|O_EXCL already refuses a symbolic link
+// in the final path element, it behaves as CreateSafe;
+// O_NOFOLLOW only matters when an existing file is
+// opened, as with OpenFile and WithNoFollow.
+func CreateSafeNoFollow(name string) (BasicFile, error) {
+	return OpenFile(name, WithFlags(os.O_RDWR|os.O_CREATE|os.O_EXCL), WithNoFollow())
 }
 
 // A BasicFile provides access to a single file as an in
@@ -148,8 +137,13 @@ type BasicFile interface {
 	fsFile
 
 	// GoFile implements the most common
-	// file functionality in Go.
-	// GoFile // TODO fix implementation ...
+	// file functionality in Go. It is not
+	// required of a BasicFile; use NewGoFile.
+	// GoFile
+
+	// FileLocker provides advisory locking of
+	// the file within and across processes.
+	FileLocker
 
 	// Dirty sets isDirty to true, forcing any
 	// cached values to be recalculated.
@@ -187,8 +181,13 @@ Sync(). Error
 
 type (
 	basicFile struct {
-		providedName     string // original user input
-		lock             bool
+		providedName     string       // original user input
+		mu               sync.RWMutex // guards File and the cached fields below
+		lk               sync.RWMutex // caller lock; see Lock and LockShared
+		flk              fileLock     // inter-process locks held on File
+		cfg              fileConfig   // options used to open File
+		ref              *fileRef     // reference count of File; nil if not open
+		owned            bool         // the basicFile holds a reference to ref
 		isDirty          bool
 		fi               os.FileInfo // cached file information
 		mode             os.FileMode // cached file mode
@@ -208,33 +207,82 @@ type (
 //
 // This implementation also has:
 //  io.Writer, io.StringWriter, io.ReaderFrom, io.WriterTo, io.ReaderAt, io.WriterAt
+//
+// The file is returned after f.mu is released, so it is
+// only valid until it is closed by Close or Flush. The
+// methods of basicFile, which hold f.mu, are used instead
+// wherever possible.
 func (f *basicFile) file() *os.File {
-	if f.File == nil || f.isDirty {
-		ff, err := os.OpenFile(f.providedName, os.O_RDWR, NormalMode)
-		if Err(err) != nil {
-			return nil
+	ff, err := f.rlockFile()
+	if err != nil {
+		return nil
+	}
+	f.mu.RUnlock()
+	return ff
+}
+
+// openLocked opens the underlying file if it is
+// not already open. The caller must hold f.mu
+// exclusively.
+func (f *basicFile) openLocked() (*os.File, error) {
+	if f.File == nil {
+		ff, err := os.OpenFile(f.providedName, f.cfg.reopenFlag(), f.cfg.perm)
+		if err != nil {
+			return nil, NewGoFileError("gofile.open", f.providedName, err)
 		}
-		f.File = ff
+		f.attachLocked(ff, true)
 	}
+	return f.File, nil
+}
 
-	return f.File
+// attachLocked makes ff the underlying file of f.
+// If owned is true, f holds a reference to it that
+// is released by closeLocked. The caller must hold
+// f.mu exclusively and f.File must be nil.
+func (f *basicFile) attachLocked(ff *os.File, owned bool) {
+	f.File = ff
+	f.ref = &fileRef{file: ff}
+	f.owned = owned
+	if owned {
+		f.ref.acquire()
+	}
+}
+
+// rlockFile returns the underlying file, opening
+// it if needed, with f.mu held for reading. The
+// caller must call f.mu.RUnlock() when finished
+// unless an error is returned.
+func (f *basicFile) rlockFile() (*os.File, error) {
+	for {
+		f.mu.RLock()
+		if f.File != nil {
+			return f.File, nil
+		}
+		f.mu.RUnlock()
+
+		f.mu.Lock()
+		_, err := f.openLocked()
+		f.mu.Unlock()
+		if err != nil {
+			return nil, Err(err)
+		}
+	}
 }
 
 func (f *basicFile) rwc() Handle {
-	f.isDirty = true
-	ff := f.file()
-	if ff == nil {
+	h, err := f.newHandle()
+	if Err(err) != nil {
 		return nil
 	}
-
-	b := bufRWC{}
-	b.ReadWriter = bufio.NewReadWriter(f.Reader().(*bufio.Reader), f.Writer().(*bufio.Writer))
-
-	return &b
+	return h
 }
 
-func (f *basicFile) Reader() io.Reader { return bufio.NewReader(f.File) }
-func (f *basicFile) Writer() io.Writer { return bufio.NewWriter(f.File) }
+// Reader and Writer return buffered readers and writers
+// of the BasicFile itself, rather than of the underlying
+// *os.File, so that they keep working, on the reopened
+// file, after a concurrent Flush or Close.
+func (f *basicFile) Reader() io.Reader { return f.cfg.newReader(f) }
+func (f *basicFile) Writer() io.Writer { return f.cfg.newWriter(f) }
 
 // Flush flushes any in-memory copy of recent changes,
 // closes the underlying file, and resets the file
@@ -247,39 +295,33 @@ func (f *basicFile) Writer() io.Writer { return bufio.NewWriter(f.File) }
 // During the flushing and closing process, any new
 // concurrent read or write operations will block and
 // be unavailable.
+//
+// While this process holds an inter-process lock on the
+// file, Flush only syncs it: closing it would release
+// the lock.
 func (f *basicFile) Flush() error {
-	if f.Locked() {
-		return ErrFileLocked
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.File == nil {
+		return nil
 	}
-	f.Lock()
-	defer f.Unlock()
-	err := Err(f.File.Sync())
+
+	err := f.File.Sync()
 	if err != nil {
-		// TODO: retry ... could get stuck here ...
-		return f.Flush()
+		return Err(NewGoFileError("gofile.Flush", f.providedName, err))
 	}
-	err = f.File.Close()
-	if err != nil {
-		Err(err)
+	if f.processLocked() {
+		f.timeStamp()
+		return nil
 	}
-	f.fi = nil
-	f.File = nil
 	f.timeStamp()
+	if err := f.closeLocked(); err != nil {
+		return Err(NewGoFileError("gofile.Flush", f.providedName, err))
+	}
 	return nil
 }
 
-func (f *basicFile) Locked() bool {
-	return f.lock
-}
-
-func (f *basicFile) Lock() {
-	f.lock = true
-}
-
-func (f *basicFile) Unlock() {
-	f.lock = false
-}
-
 // timeStamp sets the most recent mod time in
 // the basicFile struct and returns that time.
 // This is separate and unrelated from the
@@ -316,34 +358,194 @@ func (f *basicFile) Rename(newname string) error  { return os.Rename(f.Abs(), ne
 func (f *basicFile) Readlink() (string, error)    { return os.Readlink(f.Abs()) }
 func (f *basicFile) Symlink(newname string) error { return os.Symlink(f.Abs(), newname) }
 
+// Read reads up to len(b) bytes from the file and
+// stores them in b. It holds a shared lock for the
+// duration of the call.
+func (f *basicFile) Read(b []byte) (n int, err error) {
+	ff, err := f.rlockFile()
+	if err != nil {
+		return 0, err
+	}
+	defer f.mu.RUnlock()
+	return ff.Read(b)
+}
+
+// ReadAt reads len(b) bytes from the file starting
+// at byte offset off. It holds a shared lock for the
+// duration of the call.
+func (f *basicFile) ReadAt(b []byte, off int64) (n int, err error) {
+	ff, err := f.rlockFile()
+	if err != nil {
+		return 0, err
+	}
+	defer f.mu.RUnlock()
+	return ff.ReadAt(b, off)
+}
+
+// Write writes len(b) bytes from b to the file.
+//
+// The lock only protects the lifetime of the
+// underlying *os.File; concurrent writes are
+// serialized by the operating system, as with
+// *os.File itself.
+func (f *basicFile) Write(b []byte) (n int, err error) {
+	ff, err := f.rlockFile()
+	if err != nil {
+		return 0, err
+	}
+	defer f.mu.RUnlock()
+	return ff.Write(b)
+}
+
+// WriteString is like Write, but writes the contents
+// of the string s.
+func (f *basicFile) WriteString(s string) (n int, err error) {
+	ff, err := f.rlockFile()
+	if err != nil {
+		return 0, err
+	}
+	defer f.mu.RUnlock()
+	return ff.WriteString(s)
+}
+
+// ReadFrom writes the contents of r to the file. It
+// holds a shared lock for the duration of the call.
+func (f *basicFile) ReadFrom(r io.Reader) (n int64, err error) {
+	ff, err := f.rlockFile()
+	if err != nil {
+		return 0, err
+	}
+	defer f.mu.RUnlock()
+	return ff.ReadFrom(r)
+}
+
+// WriteAt writes len(b) bytes to the file starting
+// at byte offset off.
+func (f *basicFile) WriteAt(b []byte, off int64) (n int, err error) {
+	ff, err := f.rlockFile()
+	if err != nil {
+		return 0, err
+	}
+	defer f.mu.RUnlock()
+	return ff.WriteAt(b, off)
+}
+
+// Seek sets the offset for the next Read or Write
+// on the file to offset, interpreted according to
+// whence.
+func (f *basicFile) Seek(offset int64, whence int) (int64, error) {
+	ff, err := f.rlockFile()
+	if err != nil {
+		return 0, err
+	}
+	defer f.mu.RUnlock()
+	return ff.Seek(offset, whence)
+}
+
 func (f *basicFile) WriteTo(w io.Writer) (n int64, err error) {
-	return bufio.NewReader(f.File).WriteTo(w)
+	ff, err := f.rlockFile()
+	if err != nil {
+		return 0, err
+	}
+	defer f.mu.RUnlock()
+	return f.cfg.newReader(ff).WriteTo(w)
+}
+
+// Truncate changes the size of the file. It holds
+// an exclusive lock for the duration of the call.
+func (f *basicFile) Truncate(size int64) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	ff, err := f.openLocked()
+	if err != nil {
+		return Err(err)
+	}
+	f.fi = nil
+	return ff.Truncate(size)
+}
+
+// Close closes the underlying file, if it is open.
+// The BasicFile object remains available and the
+// file will be reopened as needed.
+func (f *basicFile) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.closeLocked()
+}
+
+// closeLocked closes the underlying file and clears
+// the cached file information. The caller must hold
+// f.mu exclusively.
+//
+// Closing the file would release any inter-process
+// locks held on it, so an error matching
+// ErrFileLocked is returned instead.
+//
+// If handles still refer to the file, it is only
+// detached from f; the last handle closes it.
+func (f *basicFile) closeLocked() error {
+	if f.File == nil {
+		return nil
+	}
+	if f.processLocked() {
+		return f.lockedError("gofile.Close")
+	}
+
+	ref, owned := f.ref, f.owned
+	f.File = nil
+	f.ref = nil
+	f.owned = false
+	f.fi = nil
+
+	if owned {
+		return ref.release()
+	}
+	return nil
 }
 
 func (f *basicFile) Remove() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
 	err := os.Remove(f.Abs())
 	if err != nil {
 		return err
 	}
-	return f.Close()
+	if f.processLocked() {
+		// the file stays open until the locks are
+		// released; it is closed by a later Close.
+		return nil
+	}
+	return f.closeLocked()
 }
 
 func (bf *basicFile) Create() error {
+	bf.mu.Lock()
+	defer bf.mu.Unlock()
+	if err := bf.closeLocked(); err != nil {
+		return err
+	}
+
 	f, err := os.OpenFile(bf.providedName, os.O_RDWR|os.O_CREATE|os.O_TRUNC, NormalMode)
 	if err != nil {
 		return Err(NewGoFileError("gofile.create", bf.providedName, err))
 	}
 
-	bf.File = f
+	bf.attachLocked(f, true)
 	return nil
 }
 
 func (bf *basicFile) Open() error {
+	bf.mu.Lock()
+	defer bf.mu.Unlock()
+	if err := bf.closeLocked(); err != nil {
+		return err
+	}
+
 	f, err := os.OpenFile(bf.providedName, os.O_RDONLY, NormalMode)
 	if err != nil {
 		return Err(NewGoFileError("gofile.open", bf.providedName, err))
 	}
 
-	bf.File = f
+	bf.attachLocked(f, true)
 	return nil
 }
@@ -0,0 +1,178 @@
+package basicfile
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func Test_basicFile_concurrentFlush(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "concurrent.txt")
+	bf, err := Create(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := bf.(*basicFile)
+
+	w := f.Writer().(*bufio.Writer)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(5)
+		go func() {
+			defer wg.Done()
+			if _, err := f.Write([]byte("data\n")); err != nil {
+				t.Error(err)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			if _, err := f.WriteString("data\n"); err != nil {
+				t.Error(err)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			if _, err := f.ReadFrom(strings.NewReader("data\n")); err != nil {
+				t.Error(err)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			if _, err := f.Stat(); err != nil {
+				t.Error(err)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			if err := f.Flush(); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	// The writer outlives the file it was created with.
+	if _, err := w.WriteString("last\n"); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Flush(); err != nil {
+		t.Errorf("Writer().Flush() after Flush() = %v, want nil", err)
+	}
+	if err := f.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := os.ReadFile(name); !strings.Contains(string(got), "last\n") {
+		t.Errorf("contents = %q, want the Writer() data", got)
+	}
+}
+
+func TestCreateSafe(t *testing.T) {
+	dir := t.TempDir()
+	name := filepath.Join(dir, "app.pid")
+
+	f, err := CreateSafe(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	_, err = CreateSafe(name)
+	if !errors.Is(err, ErrExist) || !errors.Is(err, fs.ErrExist) {
+		t.Errorf("CreateSafe() on existing file = %v, want ErrExist", err)
+	}
+	var gfe *GoFileError
+	if !errors.As(err, &gfe) {
+		t.Errorf("CreateSafe() error type = %T, want *GoFileError", err)
+	}
+
+	link := filepath.Join(dir, "link.pid")
+	if err := os.Symlink(filepath.Join(dir, "target.pid"), link); err != nil {
+		t.Skip(err)
+	}
+	if _, err := CreateSafe(link); err == nil {
+		t.Error("CreateSafe() through a symlink succeeded, want error")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "target.pid")); err == nil {
+		t.Error("CreateSafe() created the symlink target")
+	}
+}
+
+func TestOpenFile(t *testing.T) {
+	dir := t.TempDir()
+	secret := filepath.Join(dir, "secret")
+
+	f, err := OpenFile(secret, WithFlags(os.O_RDWR|os.O_CREATE|os.O_EXCL), WithPerm(0600), WithNoFollow())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.(*basicFile).Write([]byte("token")); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.(*basicFile).Flush(); err != nil {
+		t.Fatal(err)
+	}
+	// reopening after Flush must not fail with O_EXCL
+	if _, err := f.(*basicFile).Seek(0, io.SeekStart); err != nil {
+		t.Errorf("Seek() after Flush() = %v, want nil", err)
+	}
+	if fi, _ := os.Stat(secret); fi.Mode().Perm() != 0600&^umask(t) {
+		t.Errorf("mode = %v, want %v", fi.Mode().Perm(), os.FileMode(0600))
+	}
+
+	ro, err := OpenFile(secret, WithFlags(os.O_RDWR|os.O_TRUNC), WithReadOnly())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ro.Close()
+	if _, err := ro.(*basicFile).Write([]byte("x")); err == nil {
+		t.Error("Write() to read-only file succeeded, want error")
+	}
+	if got, _ := os.ReadFile(secret); string(got) != "token" {
+		t.Errorf("contents = %q, want %q (WithReadOnly must drop O_TRUNC)", got, "token")
+	}
+
+	excl, err := OpenFile(secret, WithFlags(os.O_RDWR|os.O_CREATE|os.O_EXCL), WithReadOnly())
+	if err != nil {
+		t.Fatalf("OpenFile() WithFlags(O_EXCL) and WithReadOnly() = %v, want nil", err)
+	}
+	defer excl.Close()
+	if flag := excl.(*basicFile).cfg.openFlag(); flag&os.O_EXCL != 0 {
+		t.Errorf("openFlag() = %#x, want O_EXCL dropped by WithReadOnly", flag)
+	}
+
+	// Only O_NOFOLLOW refuses a symlink to an existing file.
+	link := filepath.Join(dir, "link")
+	if err := os.Symlink(secret, link); err == nil && oNoFollow != 0 {
+		if _, err := OpenFile(link, WithReadOnly(), WithNoFollow()); err == nil {
+			t.Error("OpenFile() of a symlink WithNoFollow() succeeded, want error")
+		}
+		f, err := OpenFile(link, WithReadOnly())
+		if err != nil {
+			t.Errorf("OpenFile() of a symlink = %v, want nil", err)
+		} else {
+			f.Close()
+		}
+	}
+
+	if _, err := OpenFile(filepath.Join(dir, "missing")); !errors.Is(err, ErrNotExist) {
+		t.Errorf("OpenFile() on missing file = %v, want ErrNotExist", err)
+	}
+}
+
+// umask returns the process umask, as applied to newly
+// created files.
+func umask(t *testing.T) os.FileMode {
+	name := filepath.Join(t.TempDir(), "umask")
+	if err := os.WriteFile(name, nil, 0777); err != nil {
+		t.Fatal(err)
+	}
+	fi, _ := os.Stat(name)
+	return 0777 &^ fi.Mode().Perm()
+}
@@ -0,0 +1,278 @@
+package basicfile
+
+import (
+	"sort"
+	"strings"
+)
+
+type (
+	// ConfigFile is a TextFile of configuration settings,
+	// such as an INI, .env or .properties file. Settings
+	// are read and changed by key; comments, blank lines,
+	// the order of settings and the layout of unchanged
+	// lines are preserved when the file is saved.
+	//
+	// Changes are made in memory and written by Save.
+	ConfigFile interface {
+		TextFile
+
+		// Get returns the value of key and whether it
+		// is set. If a key is set more than once, the
+		// last value is returned.
+		Get(key string) (string, bool)
+
+		// Set sets the value of key, adding it if it
+		// is not set.
+		Set(key, value string) error
+
+		// Delete removes every setting of key.
+		Delete(key string) error
+
+		// Keys returns the keys that are set, in the
+		// order they first appear.
+		Keys() []string
+
+		// Map returns the settings as a map.
+		Map() map[string]string
+	}
+
+	// configSyntax parses and formats the settings of one
+	// kind of config file.
+	configSyntax interface {
+		// parse returns the settings in lines.
+		parse(lines []string) []configEntry
+
+		// format returns the text of a setting that
+		// starts with prefix, the key and separator, and
+		// whether value can be written.
+		format(prefix, value string) (string, bool)
+
+		// prefix returns the key and separator of a
+		// new setting of key in section, or "" if key
+		// is not valid.
+		prefix(section, key string) string
+
+		// sections reports whether the syntax has
+		// sections.
+		sections() bool
+
+		// header returns the line that starts section.
+		header(section string) string
+	}
+
+	// configEntry is a setting in a config file.
+	configEntry struct {
+		section     string
+		key         string
+		value       string
+		first, last int    // lines of the setting (0-based, inclusive)
+		prefix      string // text before the value: key and separator
+		suffix      string // text after the value, e.g. a comment
+	}
+
+	configFile struct {
+		textfile
+		syntax configSyntax
+	}
+)
+
+// fullKey returns the key of e as used by ConfigFile.
+func (e configEntry) fullKey() string {
+	if e.section == "" {
+		return e.key
+	}
+	return e.section + "." + e.key
+}
+
+// openConfig opens the named file as a ConfigFile using
+// syntax.
+func openConfig(name string, syntax configSyntax, opts ...TextOption) (ConfigFile, error) {
+	d := &configFile{syntax: syntax}
+	d.textfile.init(name, opts...)
+	d.streaming = false
+	if err := d.textfile.open(); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+func (d *configFile) Get(key string) (string, bool) {
+	entries, err := d.entries()
+	if err != nil {
+		return "", false
+	}
+	for i := len(entries) - 1; i >= 0; i-- {
+		if entries[i].fullKey() == key {
+			return entries[i].value, true
+		}
+	}
+	return "", false
+}
+
+func (d *configFile) Keys() []string {
+	entries, _ := d.entries()
+	seen := make(map[string]bool, len(entries))
+	keys := make([]string, 0, len(entries))
+	for _, e := range entries {
+		k := e.fullKey()
+		if !seen[k] {
+			seen[k] = true
+			keys = append(keys, k)
+		}
+	}
+	return keys
+}
+
+func (d *configFile) Map() map[string]string {
+	entries, _ := d.entries()
+	m := make(map[string]string, len(entries))
+	for _, e := range entries {
+		m[e.fullKey()] = e.value
+	}
+	return m
+}
+
+// Set sets the value of the last setting of key. If key
+// is not set, a setting is added after the last setting
+// of its section, adding the section at the end of the
+// file if needed.
+func (d *configFile) Set(key, value string) error {
+	return d.edit("gofile.Set", func(lines []string) ([]string, bool) {
+		if key == "" {
+			return nil, false
+		}
+		entries := d.syntax.parse(lines)
+		for i := len(entries) - 1; i >= 0; i-- {
+			e := entries[i]
+			if e.fullKey() == key {
+				line, ok := d.syntax.format(e.prefix, value)
+				return replaceLines(lines, e.first, e.last, line+e.suffix), ok
+			}
+		}
+
+		section, name := d.splitKey(entries, key)
+		if name == "" {
+			return nil, false
+		}
+		prefix := d.syntax.prefix(section, name)
+		if prefix == "" {
+			return nil, false
+		}
+		line, ok := d.syntax.format(prefix, value)
+		if !ok {
+			return nil, false
+		}
+
+		// Add the setting after the last line of its section.
+		at := -1
+		for _, e := range entries {
+			if e.section == section {
+				at = e.last + 1
+			}
+		}
+		if at < 0 && section != "" {
+			at = d.headerLine(lines, section)
+		}
+		switch {
+		case at >= 0:
+			return replaceLines(lines, at, at-1, line), true
+		case section == "":
+			return replaceLines(lines, d.globalEnd(lines), -1, line), true
+		}
+
+		if n := len(lines); n > 0 && strings.TrimSpace(lines[n-1]) != "" {
+			lines = append(lines, "")
+		}
+		return append(lines, d.syntax.header(section), line), true
+	})
+}
+
+func (d *configFile) Delete(key string) error {
+	return d.edit("gofile.Delete", func(lines []string) ([]string, bool) {
+		entries := d.syntax.parse(lines)
+		found := false
+		for i := len(entries) - 1; i >= 0; i-- {
+			if e := entries[i]; e.fullKey() == key {
+				lines = replaceLines(lines, e.first, e.last)
+				found = true
+			}
+		}
+		return lines, found
+	})
+}
+
+// entries returns the settings of the file.
+func (d *configFile) entries() ([]configEntry, error) {
+	lines, err := d.Lines()
+	if err != nil {
+		return nil, err
+	}
+	return d.syntax.parse(lines), nil
+}
+
+// splitKey returns the section and name of key. The
+// longest section that exists and prefixes key is used;
+// otherwise key is split at its first '.'. Syntaxes
+// without sections do not split keys.
+func (d *configFile) splitKey(entries []configEntry, key string) (section, name string) {
+	if !d.syntax.sections() {
+		return "", key
+	}
+
+	sections := map[string]bool{}
+	for _, e := range entries {
+		sections[e.section] = true
+	}
+	names := make([]string, 0, len(sections))
+	for s := range sections {
+		names = append(names, s)
+	}
+	sort.Slice(names, func(i, j int) bool { return len(names[i]) > len(names[j]) })
+
+	for _, s := range names {
+		if s != "" && strings.HasPrefix(key, s+".") {
+			return s, key[len(s)+1:]
+		}
+	}
+	if i := strings.IndexByte(key, '.'); i >= 0 {
+		return key[:i], key[i+1:]
+	}
+	return "", key
+}
+
+// headerLine returns the index of the line after the
+// header of section, or -1 if there is none.
+func (d *configFile) headerLine(lines []string, section string) int {
+	header := d.syntax.header(section)
+	for i, line := range lines {
+		if strings.TrimSpace(line) == header {
+			return i + 1
+		}
+	}
+	return -1
+}
+
+// globalEnd returns the index of the line where a setting
+// outside any section is added: before the first section
+// header, or at the end of the file.
+func (d *configFile) globalEnd(lines []string) int {
+	if !d.syntax.sections() {
+		return len(lines)
+	}
+	for i, line := range lines {
+		t := strings.TrimSpace(line)
+		if strings.HasPrefix(t, "[") && strings.HasSuffix(t, "]") {
+			return i
+		}
+	}
+	return len(lines)
+}
+
+// replaceLines returns lines with lines[first:last+1]
+// replaced by repl.
+func replaceLines(lines []string, first, last int, repl ...string) []string {
+	out := make([]string, 0, len(lines)-(last-first+1)+len(repl))
+	out = append(out, lines[:first]...)
+	out = append(out, repl...)
+	return append(out, lines[last+1:]...)
+}
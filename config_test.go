@@ -0,0 +1,207 @@
+package basicfile
+
+import (
+	"errors"
+	"os"
+	"reflect"
+	"testing"
+)
+
+const testINI = `; global settings
+name = svc
+
+[server]
+# listen address
+host = 0.0.0.0
+port: 8080
+
+[db.primary]
+url = "  postgres://db  "
+`
+
+func TestINIFile(t *testing.T) {
+	path := writeTestFile(t, "app.ini", testINI)
+
+	f, err := OpenINI(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct{ key, want string }{
+		{"name", "svc"},
+		{"server.host", "0.0.0.0"},
+		{"server.port", "8080"},
+		{"db.primary.url", "  postgres://db  "},
+	}
+	for _, tt := range tests {
+		if got, ok := f.Get(tt.key); !ok || got != tt.want {
+			t.Errorf("Get(%q) = %q, %v, want %q", tt.key, got, ok, tt.want)
+		}
+	}
+	wantKeys := []string{"name", "server.host", "server.port", "db.primary.url"}
+	if got := f.Keys(); !reflect.DeepEqual(got, wantKeys) {
+		t.Errorf("Keys() = %q, want %q", got, wantKeys)
+	}
+
+	for _, kv := range [][2]string{
+		{"server.port", "9090"},
+		{"server.debug", "true"},
+		{"db.primary.pool", "4"},
+		{"log.level", "info"},
+		{"version", "2"},
+	} {
+		if err := f.Set(kv[0], kv[1]); err != nil {
+			t.Fatalf("Set(%q) = %v", kv[0], err)
+		}
+	}
+	if err := f.Delete("server.host"); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Set("bad=key", "x"); !errors.Is(err, ErrInvalid) {
+		t.Errorf("Set(bad=key) = %v, want ErrInvalid", err)
+	}
+	if err := f.Delete("nope"); !errors.Is(err, ErrInvalid) {
+		t.Errorf("Delete(nope) = %v, want ErrInvalid", err)
+	}
+	if err := f.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	got, _ := os.ReadFile(path)
+	wantFile := `; global settings
+name = svc
+version = 2
+
+[server]
+# listen address
+port: 9090
+debug = true
+
+[db.primary]
+url = "  postgres://db  "
+pool = 4
+
+[log]
+level = info
+`
+	if string(got) != wantFile {
+		t.Errorf("saved file =\n%s\nwant\n%s", got, wantFile)
+	}
+}
+
+func TestDotenvFile(t *testing.T) {
+	t.Setenv("CONFIG_TEST_HOME", "/home/me")
+	path := writeTestFile(t, ".env", `# database
+export DB_HOST=localhost # local only
+DB_URL="postgres://${DB_HOST}:${DB_PORT:-5432}/app\n"
+LITERAL='$DB_HOST stays'
+CACHE=$CONFIG_TEST_HOME/cache
+EMPTY=
+`)
+
+	f, err := OpenDotenv(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"DB_HOST": "localhost",
+		"DB_URL":  "postgres://localhost:5432/app\n",
+		"LITERAL": "$DB_HOST stays",
+		"CACHE":   "/home/me/cache",
+		"EMPTY":   "",
+	}
+	if got := f.Map(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Map() = %q, want %q", got, want)
+	}
+
+	if err := f.Set("DB_HOST", "db.internal"); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Set("GREETING", `say "hi" for $5`); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Set("1BAD", "x"); !errors.Is(err, ErrInvalid) {
+		t.Errorf("Set(1BAD) = %v, want ErrInvalid", err)
+	}
+	if got, _ := f.Get("DB_URL"); got != "postgres://db.internal:5432/app\n" {
+		t.Errorf("Get(DB_URL) after Set = %q", got)
+	}
+	if got, _ := f.Get("GREETING"); got != `say "hi" for $5` {
+		t.Errorf("Get(GREETING) = %q", got)
+	}
+	if err := f.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	got, _ := os.ReadFile(path)
+	wantFile := `# database
+export DB_HOST=db.internal # local only
+DB_URL="postgres://${DB_HOST}:${DB_PORT:-5432}/app\n"
+LITERAL='$DB_HOST stays'
+CACHE=$CONFIG_TEST_HOME/cache
+EMPTY=
+GREETING="say \"hi\" for \$5"
+`
+	if string(got) != wantFile {
+		t.Errorf("saved file =\n%s\nwant\n%s", got, wantFile)
+	}
+}
+
+func TestPropertiesFile(t *testing.T) {
+	path := writeTestFile(t, "app.properties", `# app
+! settings
+app.name = Demo
+app.path: C:\\apps
+greeting Hello \
+         World
+key\ with\ spaces=v
+unicode=caf\u00e9 \uD83D\uDE00
+`)
+
+	f, err := OpenProperties(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"app.name":        "Demo",
+		"app.path":        `C:\apps`,
+		"greeting":        "Hello World",
+		"key with spaces": "v",
+		"unicode":         "café 😀",
+	}
+	if got := f.Map(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Map() = %q, want %q", got, want)
+	}
+
+	if err := f.Set("greeting", "Hi"); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Set("new key", "naïve"); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	got, _ := os.ReadFile(path)
+	wantFile := `# app
+! settings
+app.name = Demo
+app.path: C:\\apps
+greeting Hi
+key\ with\ spaces=v
+unicode=caf\u00e9 \uD83D\uDE00
+new\ key=na\u00EFve
+`
+	if string(got) != wantFile {
+		t.Errorf("saved file =\n%s\nwant\n%s", got, wantFile)
+	}
+
+	g, err := OpenProperties(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := g.Get("new key"); v != "naïve" {
+		t.Errorf("Get(new key) after Save = %q", v)
+	}
+}
@@ -0,0 +1,370 @@
+package basicfile
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"hash"
+	"hash/crc32"
+	"io"
+	"io/fs"
+	"sync"
+)
+
+const (
+	defaultCopyBufSize     = 1 << 20 // 1 MiB
+	defaultCopyParallelism = 4
+)
+
+// HashAlgo selects the checksum used to verify a copy.
+type HashAlgo int
+
+const (
+	NoVerify HashAlgo = iota
+	CRC32
+	MD5
+	SHA1
+	SHA256
+)
+
+// New returns a new hash.Hash for the algorithm, or nil
+// for NoVerify or an unknown algorithm.
+func (h HashAlgo) New() hash.Hash {
+	switch h {
+	case CRC32:
+		return crc32.NewIEEE()
+	case MD5:
+		return md5.New()
+	case SHA1:
+		return sha1.New()
+	case SHA256:
+		return sha256.New()
+	}
+	return nil
+}
+
+func (h HashAlgo) String() string {
+	switch h {
+	case NoVerify:
+		return "none"
+	case CRC32:
+		return "crc32"
+	case MD5:
+		return "md5"
+	case SHA1:
+		return "sha1"
+	case SHA256:
+		return "sha256"
+	}
+	return "unknown"
+}
+
+// CopyOptions configures Copy.
+type CopyOptions struct {
+	// BufferSize is the size of each chunk copied.
+	BufferSize int
+
+	// Parallelism is the number of chunks copied
+	// at the same time.
+	Parallelism int
+
+	// Progress, if not nil, is called after each chunk
+	// is written with the number of bytes copied so
+	// far (including any resumed bytes) and the total
+	// size of src, or -1 if it is unknown. Calls are
+	// serialized.
+	Progress func(done, total int64)
+
+	// Resume continues a previous, interrupted copy
+	// by skipping the bytes already present in dst.
+	// Those bytes are not checked against src; see
+	// Copy.
+	Resume bool
+
+	// Verify, if not NoVerify, compares checksums of
+	// src and dst after copying. dst must then also
+	// implement io.ReaderAt.
+	Verify HashAlgo
+}
+
+// Copy copies src to dst using ReadAt and WriteAt in
+// chunks of BufferSize bytes, with up to Parallelism
+// chunks in flight at once. It returns the number of
+// bytes copied by this call.
+//
+// Chunks are read in parallel but written in order, so
+// dst never extends past the contiguous prefix that has
+// been copied, even if the process is killed part way.
+//
+// The size of src is taken from a Stat or Size method.
+// If src has neither, it is copied sequentially until
+// io.EOF. If dst has a Truncate method, it is truncated
+// to the size of src when the copy completes, and to
+// the contiguous prefix that was copied if the copy
+// fails, so that a later copy can be resumed.
+//
+// Resume requires a Stat or Size method on dst. It
+// trusts the bytes already in dst: they are not compared
+// with src, and after a power failure a file system may
+// keep a later write but not an earlier one. Resume
+// without Verify is only safe when dst is known to be an
+// earlier, cleanly interrupted copy of src.
+//
+// If the copy fails, the error is a *GoFileError. A
+// failed verification matches ErrChecksum.
+func Copy(ctx context.Context, src io.ReaderAt, dst io.WriterAt, opts CopyOptions) (int64, error) {
+	if opts.BufferSize <= 0 {
+		opts.BufferSize = defaultCopyBufSize
+	}
+	if opts.Parallelism <= 0 {
+		opts.Parallelism = defaultCopyParallelism
+	}
+
+	total, known := sizeOf(src)
+	if !known {
+		total = -1
+	}
+
+	var start int64
+	if opts.Resume {
+		size, ok := sizeOf(dst)
+		if !ok {
+			return 0, NewGoFileError("gofile.Copy", "", ErrInvalid).WithMessage("cannot resume: destination size unknown")
+		}
+		start = size
+		if known && start > total {
+			start = total
+		}
+	}
+
+	var mu sync.Mutex
+	done := start
+	progress := func(n int) {
+		if opts.Progress == nil {
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		done += int64(n)
+		opts.Progress(done, total)
+	}
+
+	var n int64
+	var err error
+	if known {
+		n, err = copyChunks(ctx, src, dst, start, total, opts, progress)
+	} else {
+		n, err = replicate(ctx, dst, src, start, make([]byte, opts.BufferSize), progress)
+		total = start + n
+	}
+	if err != nil {
+		return n, NewGoFileError("gofile.Copy", "", err)
+	}
+
+	if t, ok := dst.(interface{ Truncate(int64) error }); ok {
+		if err := t.Truncate(total); err != nil {
+			return n, NewGoFileError("gofile.Copy", "", err)
+		}
+	}
+
+	if opts.Verify != NoVerify {
+		if err := verifyCopy(src, dst, total, opts.Verify); err != nil {
+			return n, err
+		}
+	}
+	return n, nil
+}
+
+// copyChunks copies [start, total) from src to dst.
+// Chunks are read in parallel and written in order. If
+// the copy fails and dst can be truncated, it is
+// truncated to the end of the chunks written.
+func copyChunks(ctx context.Context, src io.ReaderAt, dst io.WriterAt, start, total int64, opts CopyOptions, progress func(n int)) (int64, error) {
+	size := int64(opts.BufferSize)
+	chunks := int((total - start + size - 1) / size)
+	if chunks == 0 {
+		return 0, nil
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		mu       sync.Mutex
+		turn     = sync.NewCond(&mu)
+		firstErr error
+		written  int64
+		prefix   int // chunks [0, prefix) are written
+	)
+	fail := func(err error) {
+		if firstErr == nil {
+			firstErr = err
+		}
+		cancel()
+		turn.Broadcast()
+	}
+
+	work := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < opts.Parallelism && w < chunks; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			buf := make([]byte, size)
+			for i := range work {
+				off := start + int64(i)*size
+				b := buf
+				if rem := total - off; rem < size {
+					b = buf[:rem]
+				}
+
+				rerr := readChunk(src, b, off)
+
+				// Chunks are fed in order, so chunk prefix
+				// is always held by a worker.
+				mu.Lock()
+				for prefix != i && firstErr == nil {
+					turn.Wait()
+				}
+				if rerr != nil {
+					fail(rerr)
+				}
+				if firstErr != nil {
+					mu.Unlock()
+					continue
+				}
+				mu.Unlock()
+
+				n, err := writeChunk(dst, b, off)
+
+				mu.Lock()
+				written += int64(n)
+				if err != nil {
+					fail(err)
+				} else {
+					prefix++
+					turn.Broadcast()
+				}
+				mu.Unlock()
+
+				if err == nil {
+					progress(n)
+				}
+			}
+		}()
+	}
+
+feed:
+	for i := 0; i < chunks; i++ {
+		select {
+		case <-ctx.Done():
+			break feed
+		case work <- i:
+		}
+	}
+	close(work)
+	wg.Wait()
+
+	err := firstErr
+	if err == nil {
+		err = ctx.Err()
+	}
+	if err == nil && prefix < chunks {
+		err = io.ErrUnexpectedEOF
+	}
+	if err != nil {
+		if t, ok := dst.(interface{ Truncate(int64) error }); ok {
+			end := start + int64(prefix)*size
+			if end > total {
+				end = total
+			}
+			t.Truncate(end)
+		}
+	}
+	return written, err
+}
+
+// readChunk reads len(buf) bytes at off from src.
+func readChunk(src io.ReaderAt, buf []byte, off int64) error {
+	n, err := src.ReadAt(buf, off)
+	if err == io.EOF && n == len(buf) {
+		err = nil
+	}
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+// writeChunk writes buf at off in dst.
+func writeChunk(dst io.WriterAt, buf []byte, off int64) (int, error) {
+	n, err := dst.WriteAt(buf, off)
+	if err == nil && n < len(buf) {
+		err = io.ErrShortWrite
+	}
+	return n, err
+}
+
+// verifyCopy compares the checksums of the first size
+// bytes of src and dst.
+func verifyCopy(src io.ReaderAt, dst io.WriterAt, size int64, algo HashAlgo) error {
+	r, ok := dst.(io.ReaderAt)
+	if !ok {
+		return NewGoFileError("gofile.Copy", "", ErrInvalid).WithMessage("cannot verify: destination is not an io.ReaderAt")
+	}
+
+	want, err := checksum(src, size, algo)
+	if err != nil {
+		return NewGoFileError("gofile.Copy", "", err)
+	}
+	got, err := checksum(r, size, algo)
+	if err != nil {
+		return NewGoFileError("gofile.Copy", "", err)
+	}
+	if !bytes.Equal(got, want) {
+		return &GoFileError{
+			Op:  prependGoFilePrefix("gofile.Copy"),
+			Err: ErrChecksum,
+		}
+	}
+	return nil
+}
+
+// checksum returns the checksum of the first size bytes
+// of r.
+func checksum(r io.ReaderAt, size int64, algo HashAlgo) ([]byte, error) {
+	h := algo.New()
+	if h == nil {
+		return nil, ErrInvalid
+	}
+	n, err := io.Copy(h, io.NewSectionReader(r, 0, size))
+	if err != nil {
+		return nil, err
+	}
+	if n != size {
+		return nil, io.ErrUnexpectedEOF
+	}
+	return h.Sum(nil), nil
+}
+
+// sizeOf returns the size of v if it can be determined
+// from a Stat or Size method. Cached file information
+// is refreshed first if v has a Dirty method.
+func sizeOf(v any) (int64, bool) {
+	if d, ok := v.(interface{ Dirty() }); ok {
+		d.Dirty()
+	}
+	switch x := v.(type) {
+	case interface{ Stat() (fs.FileInfo, error) }:
+		fi, err := x.Stat()
+		if err != nil {
+			return 0, false
+		}
+		return fi.Size(), true
+	case interface{ Size() int64 }:
+		return x.Size(), true
+	}
+	return 0, false
+}
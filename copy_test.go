@@ -0,0 +1,151 @@
+package basicfile
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestCopy(t *testing.T) {
+	data := make([]byte, 100000)
+	rand.Read(data)
+
+	dir := t.TempDir()
+	srcName := filepath.Join(dir, "src.dat")
+	dstName := filepath.Join(dir, "dst.dat")
+	if err := os.WriteFile(srcName, data, NormalMode); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		partial int // bytes already present in dst
+		opts    CopyOptions
+		want    int64
+	}{
+		{"sequential", 0, CopyOptions{BufferSize: 4096, Parallelism: 1, Verify: CRC32}, 100000},
+		{"parallel", 0, CopyOptions{BufferSize: 1000, Parallelism: 8, Verify: SHA256}, 100000},
+		{"resume", 30000, CopyOptions{BufferSize: 4096, Resume: true, Verify: MD5}, 70000},
+		{"overwrite", 30000, CopyOptions{BufferSize: 4096}, 100000},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := os.WriteFile(dstName, data[:tt.partial], NormalMode); err != nil {
+				t.Fatal(err)
+			}
+			src, _ := Open(srcName)
+			dst, _ := NewBasicFile(dstName)
+			defer src.Close()
+			defer dst.Close()
+
+			var last int64
+			tt.opts.Progress = func(done, total int64) {
+				if total != int64(len(data)) {
+					t.Errorf("Progress() total = %d, want %d", total, len(data))
+				}
+				last = done
+			}
+
+			n, err := Copy(context.Background(), src.(*basicFile), dst.(*basicFile), tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if n != tt.want {
+				t.Errorf("Copy() = %d, want %d", n, tt.want)
+			}
+			if last != int64(len(data)) {
+				t.Errorf("last Progress() done = %d, want %d", last, len(data))
+			}
+			got, _ := os.ReadFile(dstName)
+			if !bytes.Equal(got, data) {
+				t.Error("destination does not match source")
+			}
+		})
+	}
+}
+
+func TestCopy_verifyMismatch(t *testing.T) {
+	src := bytes.NewReader([]byte("hello, world"))
+	dst := &corruptWriter{}
+
+	_, err := Copy(context.Background(), src, dst, CopyOptions{Verify: SHA1})
+	if !errors.Is(err, ErrChecksum) {
+		t.Errorf("Copy() = %v, want ErrChecksum", err)
+	}
+}
+
+// corruptWriter stores the bytes written to it with
+// the first byte altered.
+type corruptWriter struct{ b []byte }
+
+func (w *corruptWriter) WriteAt(p []byte, off int64) (int, error) {
+	if end := int(off) + len(p); end > len(w.b) {
+		w.b = append(w.b, make([]byte, end-len(w.b))...)
+	}
+	copy(w.b[off:], p)
+	w.b[0] ^= 0xff
+	return len(p), nil
+}
+
+func (w *corruptWriter) ReadAt(p []byte, off int64) (int, error) {
+	return bytes.NewReader(w.b).ReadAt(p, off)
+}
+
+func TestCopy_resumeAfterInterruption(t *testing.T) {
+	data := make([]byte, 8000)
+	rand.Read(data)
+
+	// The first chunk fails slowly, as if the process
+	// were killed while writing it; dst has no Truncate
+	// method, so nothing is cleaned up.
+	dst := &memWriter{failAt: 0}
+	_, err := Copy(context.Background(), bytes.NewReader(data), dst, CopyOptions{BufferSize: 1000, Parallelism: 8})
+	if err == nil {
+		t.Fatal("Copy() succeeded, want error")
+	}
+	if dst.Size() != 0 {
+		t.Errorf("dst size after failure = %d, want 0: later chunks were written past a gap", dst.Size())
+	}
+
+	dst.failAt = -1
+	if _, err := Copy(context.Background(), bytes.NewReader(data), dst, CopyOptions{BufferSize: 1000, Parallelism: 8, Resume: true}); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(dst.b, data) {
+		t.Error("resumed destination does not match source")
+	}
+}
+
+// memWriter stores the bytes written to it. A write at
+// offset failAt fails after a delay.
+type memWriter struct {
+	mu     sync.Mutex
+	b      []byte
+	failAt int64
+}
+
+func (w *memWriter) WriteAt(p []byte, off int64) (int, error) {
+	if off == w.failAt {
+		time.Sleep(20 * time.Millisecond)
+		return 0, ErrShortWrite
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if end := int(off) + len(p); end > len(w.b) {
+		w.b = append(w.b, make([]byte, end-len(w.b))...)
+	}
+	copy(w.b[off:], p)
+	return len(p), nil
+}
+
+func (w *memWriter) Size() int64 {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return int64(len(w.b))
+}
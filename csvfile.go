@@ -0,0 +1,560 @@
+package basicfile
+
+import (
+	"encoding/csv"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// HeaderMode selects how the header row of a CSVFile
+// is found.
+type HeaderMode int
+
+const (
+	// HeaderAuto detects whether the first row is a
+	// header by comparing it with the rows after it.
+	HeaderAuto HeaderMode = iota
+
+	// HeaderPresent treats the first row as a header.
+	HeaderPresent
+
+	// HeaderAbsent treats every row as data.
+	HeaderAbsent
+)
+
+// headerSample is the number of rows read to detect a
+// header.
+const headerSample = 20
+
+type (
+	// CSVFile is a TextFile of delimited records, such
+	// as CSV or TSV, as read and written by encoding/csv.
+	// Quoted fields may contain delimiters, quotes and
+	// line breaks. Rows may have different numbers of
+	// fields; missing fields are empty.
+	//
+	// Records returns every record, including the header.
+	CSVFile interface {
+		TextFile
+
+		// Header returns the names of the columns, or
+		// nil if the file has no header.
+		Header() []string
+
+		// Rows returns an iterator that streams the
+		// data rows of the file from disk.
+		Rows() CSVRowIterator
+
+		// ReadAll returns the data rows of the file.
+		ReadAll() ([]CSVRow, error)
+
+		// Append adds rows to the end of the file.
+		Append(rows ...[]string) error
+
+		// Validate checks every data row of the file
+		// against schema.
+		Validate(schema Schema) error
+	}
+
+	// CSVRowIterator streams the data rows of a CSVFile.
+	CSVRowIterator interface {
+		io.Closer
+
+		// Next advances to the next row. It returns
+		// false at the end of the file or on error.
+		Next() bool
+
+		// Row returns the current row.
+		Row() CSVRow
+
+		// Err returns the first error other than io.EOF
+		// encountered by Next.
+		Err() error
+	}
+
+	// CSVRow is a data row of a CSVFile.
+	CSVRow struct {
+		Fields []string
+		Num    int // 1-based row number, not counting the header
+		Line   int // 1-based line number of the start of the row
+
+		cols map[string]int // column name -> index
+		path string
+	}
+
+	// A CSVOption configures a CSVFile.
+	CSVOption func(*csvFile)
+
+	csvFile struct {
+		textfile
+		headerMode HeaderMode
+		comment    rune
+		lazyQuotes bool
+		header     []string
+		cols       map[string]int
+	}
+
+	csvRowIter struct {
+		f    *os.File
+		r    *csv.Reader
+		name string
+		cols map[string]int
+		skip bool // the next record is the header
+		row  CSVRow
+		err  error
+		done bool
+	}
+)
+
+// WithDelimiter sets the field delimiter, which must be
+// a single byte. It is the record separator of the
+// TextFile, so the default is '\t'; use ',' for CSV.
+func WithDelimiter(c byte) CSVOption {
+	return func(d *csvFile) { d.recordsep = c }
+}
+
+// WithHeader sets how the header row is found. The
+// default is HeaderAuto.
+func WithHeader(mode HeaderMode) CSVOption {
+	return func(d *csvFile) { d.headerMode = mode }
+}
+
+// WithComment ignores lines that begin with c.
+func WithComment(c rune) CSVOption {
+	return func(d *csvFile) { d.comment = c }
+}
+
+// WithLazyQuotes allows a quote to appear in an unquoted
+// field and a non-doubled quote to appear in a quoted
+// field, as csv.Reader.LazyQuotes does.
+func WithLazyQuotes() CSVOption {
+	return func(d *csvFile) { d.lazyQuotes = true }
+}
+
+// WithTextOptions configures the underlying TextFile,
+// for example its encoding or streaming.
+func WithTextOptions(opts ...TextOption) CSVOption {
+	return func(d *csvFile) {
+		for _, opt := range opts {
+			opt(&d.textfile)
+		}
+	}
+}
+
+// OpenCSV opens the named file as a CSVFile and reads its
+// header, if it has one.
+//
+// If there is an error, it will be of type *GoFileError.
+func OpenCSV(name string, opts ...CSVOption) (CSVFile, error) {
+	d := &csvFile{}
+	d.textfile.init(name)
+	for _, opt := range opts {
+		opt(d)
+	}
+	if err := d.textfile.open(); err != nil {
+		return nil, err
+	}
+	if err := d.readHeader(); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+func (d *csvFile) Header() []string {
+	return d.header
+}
+
+// Records returns every record of the file, including
+// the header, parsed as encoding/csv does. Unsaved edits
+// are included.
+func (d *csvFile) Records() ([][]string, error) {
+	d.tmu.Lock()
+	defer d.tmu.Unlock()
+
+	d.refresh()
+	if d.records == nil {
+		s, err := d.text()
+		if err != nil {
+			return nil, err
+		}
+		records, err := d.newReader(strings.NewReader(s)).ReadAll()
+		if err != nil {
+			return nil, d.csvError("gofile.Records", err)
+		}
+		if records == nil {
+			records = [][]string{}
+		}
+		d.records = records
+	}
+	return d.records, nil
+}
+
+// Rows returns an iterator over the data rows of the
+// file, streamed from disk. Unsaved edits are not
+// included.
+//
+// If the file cannot be opened, the error is returned
+// by Err and Next returns false.
+func (d *csvFile) Rows() CSVRowIterator {
+	it := &csvRowIter{
+		name: d.providedName,
+		cols: d.cols,
+		skip: d.header != nil,
+	}
+	it.r, it.f, it.err = d.reader()
+	if it.err != nil {
+		it.done = true
+	}
+	return it
+}
+
+// ReadAll returns the data rows of the file, read as
+// Rows does.
+func (d *csvFile) ReadAll() ([]CSVRow, error) {
+	it := d.Rows()
+	defer it.Close()
+
+	rows := []CSVRow{}
+	for it.Next() {
+		rows = append(rows, it.Row())
+	}
+	return rows, it.Err()
+}
+
+// Append adds rows to the end of the file on disk,
+// quoted as needed, in the encoding and with the line
+// endings of the file. A line ending is added first if
+// the file does not end with one.
+//
+// Append fails if there are unsaved edits.
+//
+// If there is an error, it will be of type *GoFileError.
+func (d *csvFile) Append(rows ...[]string) error {
+	d.tmu.Lock()
+	defer d.tmu.Unlock()
+
+	var sb strings.Builder
+	w := csv.NewWriter(&sb)
+	w.Comma = rune(d.recordsep)
+	w.UseCRLF = d.eol == "\r\n"
+	if err := w.WriteAll(rows); err != nil {
+		return Err(NewGoFileError("gofile.Append", d.providedName, err))
+	}
+
+	return d.appendText("gofile.Append", sb.String(), false)
+}
+
+// Validate checks every data row of the file, streamed
+// from disk, against schema. Columns are matched to the
+// header by name or, if there is no header, by position.
+//
+// The first value that is missing or cannot be parsed
+// is reported as a *GoFileError wrapping a *FieldError.
+func (d *csvFile) Validate(schema Schema) error {
+	index := make([]int, len(schema))
+	for i, c := range schema {
+		if d.header == nil {
+			index[i] = i
+			continue
+		}
+		j, ok := d.cols[c.Name]
+		if !ok {
+			return fieldError("gofile.Validate", d.providedName, &FieldError{
+				Line:   1,
+				Column: c.Name,
+				Err:    ErrNotExist,
+			})
+		}
+		index[i] = j
+	}
+
+	it := d.Rows()
+	defer it.Close()
+
+	for it.Next() {
+		row := it.Row()
+		for i, c := range schema {
+			if err := c.check(row.Field(index[i])); err != nil {
+				return fieldError("gofile.Validate", d.providedName, &FieldError{
+					Row:    row.Num,
+					Line:   row.Line,
+					Column: c.Name,
+					Err:    err,
+				})
+			}
+		}
+	}
+	return it.Err()
+}
+
+// readHeader reads the header row as configured by the
+// header mode.
+func (d *csvFile) readHeader() error {
+	if d.headerMode == HeaderAbsent {
+		return nil
+	}
+
+	r, f, err := d.reader()
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var sample [][]string
+	for len(sample) <= headerSample {
+		record, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return d.csvError("gofile.OpenCSV", err)
+		}
+		sample = append(sample, record)
+	}
+
+	if len(sample) == 0 {
+		return nil
+	}
+	if d.headerMode == HeaderPresent || looksLikeHeader(sample) {
+		d.header = sample[0]
+		d.cols = make(map[string]int, len(d.header))
+		for i, name := range d.header {
+			if _, ok := d.cols[name]; !ok {
+				d.cols[name] = i
+			}
+		}
+	}
+	return nil
+}
+
+// looksLikeHeader reports whether the first of records
+// is a header. It must have unique, non-empty and
+// non-numeric values. Each column then votes: a numeric
+// column, or one with at least two values that all have
+// the same length, votes for a header if the first value
+// differs from the others in kind and against it
+// otherwise. As with Python's csv.Sniffer, a tie is not
+// a header, so neither is a single row.
+func looksLikeHeader(records [][]string) bool {
+	first := records[0]
+	seen := make(map[string]bool, len(first))
+	for _, s := range first {
+		if s == "" || seen[s] || isNumber(s) {
+			return false
+		}
+		seen[s] = true
+	}
+
+	votes := 0
+	for j, name := range first {
+		numeric, length, count := true, -1, 0
+		for _, record := range records[1:] {
+			if j >= len(record) {
+				continue
+			}
+			v := record[j]
+			count++
+			numeric = numeric && isNumber(v)
+			switch {
+			case length == -1:
+				length = len(v)
+			case length != len(v):
+				length = -2
+			}
+		}
+		switch {
+		case length == -1:
+			// no values
+		case numeric:
+			votes++
+		case count < 2:
+			// one value has no length pattern
+		case length >= 0 && length != len(name):
+			votes++
+		case length >= 0:
+			votes--
+		}
+	}
+	return votes > 0
+}
+
+// isNumber reports whether s is a decimal number.
+func isNumber(s string) bool {
+	_, err := strconv.ParseFloat(s, 64)
+	return err == nil
+}
+
+// reader returns a csv.Reader for the file, streamed from
+// disk, and the file, which the caller must close.
+func (d *csvFile) reader() (*csv.Reader, *os.File, error) {
+	f, err := os.Open(d.providedName)
+	if err != nil {
+		return nil, nil, Err(NewGoFileError("gofile.Rows", d.providedName, err))
+	}
+
+	d.tmu.Lock()
+	bom, enc := d.encodingAt(f)
+	d.tmu.Unlock()
+
+	off := int64(len(bom.Bytes()))
+	if _, err := f.Seek(off, io.SeekStart); err != nil {
+		f.Close()
+		return nil, nil, Err(NewGoFileError("gofile.Rows", d.providedName, err))
+	}
+	return d.newReader(newDecodeReader(f, enc, off)), f, nil
+}
+
+// newReader returns a csv.Reader for r configured for
+// the file.
+func (d *csvFile) newReader(r io.Reader) *csv.Reader {
+	cr := csv.NewReader(r)
+	cr.Comma = rune(d.recordsep)
+	cr.Comment = d.comment
+	cr.LazyQuotes = d.lazyQuotes
+	cr.FieldsPerRecord = -1
+	return cr
+}
+
+// csvError returns err, an error from csv.Reader, as a
+// *GoFileError.
+func (d *csvFile) csvError(op string, err error) error {
+	return Err(&GoFileError{
+		Op:   prependGoFilePrefix(op),
+		Path: d.providedName,
+		Err:  err,
+	})
+}
+
+func (it *csvRowIter) Next() bool {
+	if it.done {
+		return false
+	}
+
+	record, err := it.r.Read()
+	if err == nil && it.skip {
+		it.skip = false
+		record, err = it.r.Read()
+	}
+	if err != nil {
+		it.done = true
+		if err != io.EOF {
+			it.err = Err(&GoFileError{
+				Op:   prependGoFilePrefix("gofile.Rows"),
+				Path: it.name,
+				Err:  err,
+			})
+		}
+		return false
+	}
+
+	line, _ := it.r.FieldPos(0)
+	it.row = CSVRow{
+		Fields: record,
+		Num:    it.row.Num + 1,
+		Line:   line,
+		cols:   it.cols,
+		path:   it.name,
+	}
+	return true
+}
+
+func (it *csvRowIter) Row() CSVRow { return it.row }
+func (it *csvRowIter) Err() error  { return it.err }
+
+// Close closes the file. It is safe to call more
+// than once.
+func (it *csvRowIter) Close() error {
+	it.done = true
+	if it.f == nil {
+		return nil
+	}
+	f := it.f
+	it.f = nil
+	return f.Close()
+}
+
+// Field returns field i (0-based) of the row, or "" if
+// the row has fewer fields.
+func (r CSVRow) Field(i int) string {
+	if i < 0 || i >= len(r.Fields) {
+		return ""
+	}
+	return r.Fields[i]
+}
+
+// Get returns the value of the named column.
+func (r CSVRow) Get(col string) (string, error) {
+	v, err := r.value("gofile.Get", Column{Name: col})
+	if err != nil {
+		return "", err
+	}
+	return v.(string), nil
+}
+
+// Int returns the value of the named column as an int64.
+func (r CSVRow) Int(col string) (int64, error) {
+	v, err := r.value("gofile.Int", Column{Name: col, Type: ColumnInt, Required: true})
+	if err != nil {
+		return 0, err
+	}
+	return v.(int64), nil
+}
+
+// Float returns the value of the named column as a
+// float64.
+func (r CSVRow) Float(col string) (float64, error) {
+	v, err := r.value("gofile.Float", Column{Name: col, Type: ColumnFloat, Required: true})
+	if err != nil {
+		return 0, err
+	}
+	return v.(float64), nil
+}
+
+// Bool returns the value of the named column as a bool,
+// as strconv.ParseBool does.
+func (r CSVRow) Bool(col string) (bool, error) {
+	v, err := r.value("gofile.Bool", Column{Name: col, Type: ColumnBool, Required: true})
+	if err != nil {
+		return false, err
+	}
+	return v.(bool), nil
+}
+
+// Time returns the value of the named column as a
+// time.Time in layout, or time.RFC3339 if layout is "".
+func (r CSVRow) Time(col, layout string) (time.Time, error) {
+	v, err := r.value("gofile.Time", Column{Name: col, Type: ColumnTime, Required: true, Layout: layout})
+	if err != nil {
+		return time.Time{}, err
+	}
+	return v.(time.Time), nil
+}
+
+// value returns the value of column c parsed as its type.
+// If the column does not exist, or the value is missing
+// or cannot be parsed, the error is a *GoFileError
+// wrapping a *FieldError.
+func (r CSVRow) value(op string, c Column) (interface{}, error) {
+	fe := &FieldError{Row: r.Num, Line: r.Line, Column: c.Name}
+
+	i, ok := r.cols[c.Name]
+	if !ok {
+		fe.Err = ErrNotExist
+		return nil, fieldError(op, r.path, fe)
+	}
+
+	s := r.Field(i)
+	if s == "" && c.Required {
+		fe.Err = ErrMissingValue
+		return nil, fieldError(op, r.path, fe)
+	}
+	v, err := c.parse(s)
+	if err != nil {
+		fe.Err = err
+		return nil, fieldError(op, r.path, fe)
+	}
+	return v, nil
+}
@@ -0,0 +1,179 @@
+package basicfile
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// excelCSV is a file as saved by Excel as "CSV UTF-8".
+const excelCSV = "\xEF\xBB\xBFname,qty,price,when\r\n" +
+	"\"Widget, large\",3,4.50,2024-01-02T03:04:05Z\r\n" +
+	"\"Say \"\"hi\"\"\",10,0.99,2024-02-03T00:00:00Z\r\n" +
+	"\"two\r\nlines\",1,,2024-03-04T00:00:00Z\r\n"
+
+func TestOpenCSV(t *testing.T) {
+	path := writeTestFile(t, "excel.csv", excelCSV)
+
+	f, err := OpenCSV(path, WithDelimiter(','))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"name", "qty", "price", "when"}; !reflect.DeepEqual(f.Header(), want) {
+		t.Errorf("Header() = %q, want %q", f.Header(), want)
+	}
+
+	rows, err := f.ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 3 {
+		t.Fatalf("ReadAll() returned %d rows, want 3", len(rows))
+	}
+
+	r := rows[1]
+	if s, _ := r.Get("name"); s != `Say "hi"` {
+		t.Errorf("Get(name) = %q", s)
+	}
+	if n, err := r.Int("qty"); err != nil || n != 10 {
+		t.Errorf("Int(qty) = %d, %v", n, err)
+	}
+	if p, err := r.Float("price"); err != nil || p != 0.99 {
+		t.Errorf("Float(price) = %v, %v", p, err)
+	}
+	if w, err := r.Time("when", ""); err != nil || !w.Equal(time.Date(2024, 2, 3, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Time(when) = %v, %v", w, err)
+	}
+
+	r = rows[2]
+	if s, _ := r.Get("name"); s != "two\r\nlines" && s != "two\nlines" {
+		t.Errorf("Get(name) = %q", s)
+	}
+	if r.Num != 3 || r.Line != 4 {
+		t.Errorf("row = %d, line = %d, want 3, 4", r.Num, r.Line)
+	}
+	_, err = r.Float("price")
+	var fe *FieldError
+	if !errors.As(err, &fe) || !errors.Is(err, ErrMissingValue) || fe.Row != 3 || fe.Column != "price" {
+		t.Errorf("Float(price) on empty value = %v", err)
+	}
+	if _, err := r.Get("missing"); !errors.Is(err, ErrNotExist) {
+		t.Errorf("Get(missing) = %v, want ErrNotExist", err)
+	}
+
+	records, err := f.Records()
+	if err != nil || len(records) != 4 {
+		t.Errorf("Records() = %d records, %v, want 4", len(records), err)
+	}
+}
+
+func TestCSVFileAppend(t *testing.T) {
+	path := writeTestFile(t, "excel.csv", excelCSV)
+
+	f, err := OpenCSV(path, WithDelimiter(','), WithTextOptions(WithStreaming()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Append([]string{"new, item", "2", "1", "2024-04-05T00:00:00Z"}); err != nil {
+		t.Fatal(err)
+	}
+
+	b, _ := os.ReadFile(path)
+	if want := excelCSV + "\"new, item\",2,1,2024-04-05T00:00:00Z\r\n"; string(b) != want {
+		t.Errorf("after Append file = %q, want %q", b, want)
+	}
+	rows, err := f.ReadAll()
+	if err != nil || len(rows) != 4 {
+		t.Errorf("ReadAll() after Append = %d rows, %v, want 4", len(rows), err)
+	}
+}
+
+func TestCSVFileRoundTrip(t *testing.T) {
+	records := [][]string{
+		{"id", "text"},
+		{"1", "plain"},
+		{"2", "tab\tand \"quotes\""},
+		{"3", "line\nbreak"},
+	}
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	w.Comma = '\t'
+	w.WriteAll(records)
+
+	path := writeTestFile(t, "data.tsv", buf.String())
+	f, err := OpenCSV(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := f.Records()
+	if err != nil || !reflect.DeepEqual(got, records) {
+		t.Errorf("Records() = %q, %v, want %q", got, err, records)
+	}
+
+	if err := f.Append([]string{"4", "more\ttabs"}); err != nil {
+		t.Fatal(err)
+	}
+	w.Write([]string{"4", "more\ttabs"})
+	w.Flush()
+	if b, _ := os.ReadFile(path); string(b) != buf.String() {
+		t.Errorf("after Append file = %q, want %q", b, buf.String())
+	}
+}
+
+func TestCSVFileValidate(t *testing.T) {
+	path := writeTestFile(t, "excel.csv", excelCSV)
+	f, err := OpenCSV(path, WithDelimiter(','))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	schema := Schema{
+		{Name: "name", Required: true},
+		{Name: "qty", Type: ColumnInt},
+		{Name: "when", Type: ColumnTime},
+	}
+	if err := f.Validate(schema); err != nil {
+		t.Errorf("Validate() = %v", err)
+	}
+
+	schema = append(schema, Column{Name: "price", Type: ColumnFloat, Required: true})
+	err = f.Validate(schema)
+	var fe *FieldError
+	if _, ok := err.(*GoFileError); !ok || !errors.As(err, &fe) {
+		t.Fatalf("Validate() = %v, want *GoFileError wrapping *FieldError", err)
+	}
+	if fe.Row != 3 || fe.Line != 4 || fe.Column != "price" {
+		t.Errorf("Validate() = %v, want row 3, line 4, column price", fe)
+	}
+
+	err = f.Validate(Schema{{Name: "nope"}})
+	if !errors.Is(err, ErrNotExist) {
+		t.Errorf("Validate() with unknown column = %v, want ErrNotExist", err)
+	}
+}
+
+func TestLooksLikeHeader(t *testing.T) {
+	tests := []struct {
+		name    string
+		records [][]string
+		want    bool
+	}{
+		{"numeric data", [][]string{{"a", "b"}, {"1", "2"}, {"3", "4"}}, true},
+		{"all numeric", [][]string{{"1", "2"}, {"3", "4"}}, false},
+		{"fixed length", [][]string{{"code"}, {"AB"}, {"CD"}}, true},
+		{"same length", [][]string{{"AA"}, {"AB"}, {"CD"}}, false},
+		{"duplicate names", [][]string{{"a", "a"}, {"1", "2"}}, false},
+		{"empty name", [][]string{{"a", ""}, {"1", "2"}}, false},
+		{"tie", [][]string{{"alice", "paris"}, {"bob", "london"}}, false},
+		{"single row", [][]string{{"name", "city"}}, false},
+	}
+	for _, tt := range tests {
+		if got := looksLikeHeader(tt.records); got != tt.want {
+			t.Errorf("%s: looksLikeHeader() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
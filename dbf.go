@@ -0,0 +1,196 @@
+package basicfile
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DBFField is an attribute field of the .dbf file of a
+// Shapefile. The values of a record's attributes depend
+// on the field type:
+//
+//	'C' (character)        string, without trailing spaces
+//	'N', 'F' (numeric)     int64 if Decimals is 0 and the
+//	                       value is an integer, else float64
+//	'L' (logical)          bool
+//	'D' (date, YYYYMMDD)   time.Time, in UTC
+//	other                  string, trimmed of spaces
+//
+// Blank numeric, logical and date values are nil.
+type DBFField struct {
+	Name     string
+	Type     byte
+	Length   int
+	Decimals int
+}
+
+// dbfFile reads the records of a dBase III file.
+type dbfFile struct {
+	name       string
+	f          *os.File
+	count      int // number of records
+	headerLen  int64
+	recordLen  int64
+	fields     []DBFField
+	fieldStart []int // offset of each field in a record
+}
+
+const (
+	dbfHeaderSize = 32
+	dbfFieldSize  = 32
+)
+
+// openDBF opens the named dBase file and reads its
+// header.
+func openDBF(name string) (*dbfFile, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	d := &dbfFile{name: name, f: f}
+	if err := d.readHeader(); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return d, nil
+}
+
+func (d *dbfFile) readHeader() error {
+	var h [dbfHeaderSize]byte
+	if _, err := io.ReadFull(d.f, h[:]); err != nil {
+		return fmt.Errorf("dbf header: %w", ErrInvalid)
+	}
+	d.count = int(binary.LittleEndian.Uint32(h[4:]))
+	d.headerLen = int64(binary.LittleEndian.Uint16(h[8:]))
+	d.recordLen = int64(binary.LittleEndian.Uint16(h[10:]))
+	if d.headerLen < dbfHeaderSize+1 {
+		return fmt.Errorf("dbf header length %d: %w", d.headerLen, ErrInvalid)
+	}
+
+	b := make([]byte, d.headerLen-dbfHeaderSize)
+	if _, err := io.ReadFull(d.f, b); err != nil {
+		return fmt.Errorf("dbf field descriptors: %w", ErrInvalid)
+	}
+
+	start := 1 // after the deletion flag
+	for len(b) >= dbfFieldSize && b[0] != 0x0D {
+		fd := b[:dbfFieldSize]
+		b = b[dbfFieldSize:]
+
+		name := fd[:11]
+		if i := bytes.IndexByte(name, 0); i >= 0 {
+			name = name[:i]
+		}
+		field := DBFField{
+			Name:     strings.TrimSpace(string(name)),
+			Type:     fd[11],
+			Length:   int(fd[16]),
+			Decimals: int(fd[17]),
+		}
+		d.fields = append(d.fields, field)
+		d.fieldStart = append(d.fieldStart, start)
+		start += field.Length
+	}
+	if int64(start) > d.recordLen {
+		return fmt.Errorf("dbf fields are longer than the record length %d: %w", d.recordLen, ErrInvalid)
+	}
+	return nil
+}
+
+// record returns the attributes of record n (1-based)
+// and whether it is marked deleted.
+func (d *dbfFile) record(n int) (map[string]interface{}, bool, error) {
+	if d.f == nil {
+		return nil, false, ErrClosed
+	}
+	b := make([]byte, d.recordLen)
+	off := d.headerLen + int64(n-1)*d.recordLen
+	if _, err := d.f.ReadAt(b, off); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, false, fmt.Errorf("dbf record %d: %w", n, err)
+	}
+
+	attrs := make(map[string]interface{}, len(d.fields))
+	for i, field := range d.fields {
+		raw := b[d.fieldStart[i] : d.fieldStart[i]+field.Length]
+		v, err := field.parse(raw)
+		if err != nil {
+			return nil, false, fmt.Errorf("dbf record %d, field %s: %w", n, field.Name, err)
+		}
+		attrs[field.Name] = v
+	}
+	return attrs, b[0] == '*', nil
+}
+
+// parse returns the value of field stored in raw.
+func (field DBFField) parse(raw []byte) (interface{}, error) {
+	s := dbfString(raw)
+	switch field.Type {
+	case 'C':
+		return strings.TrimRight(s, " \x00"), nil
+
+	case 'N', 'F':
+		s = strings.TrimSpace(strings.Trim(s, "\x00"))
+		if s == "" || strings.Trim(s, "*") == "" {
+			return nil, nil
+		}
+		if field.Decimals == 0 {
+			if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+				return i, nil
+			}
+		}
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return nil, fmt.Errorf("bad number %q: %w", s, ErrInvalid)
+		}
+		return f, nil
+
+	case 'L':
+		switch strings.TrimSpace(s) {
+		case "T", "t", "Y", "y":
+			return true, nil
+		case "F", "f", "N", "n":
+			return false, nil
+		}
+		return nil, nil
+
+	case 'D':
+		s = strings.TrimSpace(strings.Trim(s, "\x00"))
+		if s == "" || strings.Trim(s, "0") == "" {
+			return nil, nil
+		}
+		t, err := time.Parse("20060102", s)
+		if err != nil {
+			return nil, fmt.Errorf("bad date %q: %w", s, ErrInvalid)
+		}
+		return t, nil
+	}
+	return strings.TrimSpace(s), nil
+}
+
+// dbfString decodes raw as UTF-8 if it is valid UTF-8
+// and in its legacyEncoding otherwise.
+func dbfString(raw []byte) string {
+	if _, ok := validUTF8(raw, true); ok {
+		return string(raw)
+	}
+	s, _ := decode(raw, legacyEncoding(raw), true)
+	return s
+}
+
+func (d *dbfFile) close() error {
+	if d.f == nil {
+		return nil
+	}
+	f := d.f
+	d.f = nil
+	return f.Close()
+}
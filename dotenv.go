@@ -0,0 +1,229 @@
+package basicfile
+
+import (
+	"os"
+	"strings"
+)
+
+// dotenvSyntax is the syntax of .env files:
+//
+//	# comment
+//	export PATH_PREFIX=/opt
+//	NAME=value # comment
+//	GREETING="hello\n$NAME"
+//	LITERAL='no $expansion here'
+type dotenvSyntax struct{}
+
+// OpenDotenv opens the named .env file as a ConfigFile.
+// An "export " before a key is allowed and ignored.
+// Values may be unquoted, with an optional " #" comment
+// after them, in single quotes, which are literal, or in
+// double quotes, which allow the escapes \n, \r, \t, \",
+// \\ and \$.
+//
+// $NAME, ${NAME} and ${NAME:-default} in unquoted and
+// double-quoted values are replaced by the value of NAME
+// set earlier in the file or, failing that, in the
+// environment. Get returns expanded values; Set writes
+// its value quoted so that it is read back unchanged.
+//
+// If there is an error, it will be of type *GoFileError.
+func OpenDotenv(name string, opts ...TextOption) (ConfigFile, error) {
+	return openConfig(name, dotenvSyntax{}, opts...)
+}
+
+func (dotenvSyntax) parse(lines []string) []configEntry {
+	var entries []configEntry
+	vars := map[string]string{}
+	lookup := func(name string) (string, bool) {
+		if v, ok := vars[name]; ok {
+			return v, true
+		}
+		return os.LookupEnv(name)
+	}
+
+	for i, line := range lines {
+		s := strings.TrimLeft(line, " \t")
+		if s == "" || s[0] == '#' {
+			continue
+		}
+		if strings.HasPrefix(s, "export ") {
+			s = strings.TrimLeft(s[len("export "):], " \t")
+		}
+		j := strings.IndexByte(s, '=')
+		if j < 0 {
+			continue
+		}
+		key := strings.TrimSpace(s[:j])
+		if !isEnvName(key) {
+			continue
+		}
+
+		v := strings.TrimLeft(s[j+1:], " \t")
+		value, suffix := dotenvValue(v, lookup)
+		vars[key] = value
+		entries = append(entries, configEntry{
+			key:    key,
+			value:  value,
+			first:  i,
+			last:   i,
+			prefix: line[:len(line)-len(v)],
+			suffix: suffix,
+		})
+	}
+	return entries
+}
+
+// dotenvValue returns the value at the start of s, the
+// text after '=', expanded using lookup, and the text
+// after the value.
+func dotenvValue(s string, lookup func(string) (string, bool)) (value, suffix string) {
+	switch {
+	case strings.HasPrefix(s, "'"):
+		if j := strings.IndexByte(s[1:], '\''); j >= 0 {
+			return s[1 : j+1], s[j+2:]
+		}
+	case strings.HasPrefix(s, `"`):
+		for j := 1; j < len(s); j++ {
+			switch s[j] {
+			case '\\':
+				j++
+			case '"':
+				return expandEnv(s[1:j], true, lookup), s[j+1:]
+			}
+		}
+	}
+
+	// Unquoted, or an unclosed quote: the value runs to
+	// a comment or the end of the line.
+	end := len(s)
+	for j := 1; j < len(s); j++ {
+		if s[j] == '#' && (s[j-1] == ' ' || s[j-1] == '\t') {
+			end = j
+			break
+		}
+	}
+	v := strings.TrimRight(s[:end], " \t")
+	return expandEnv(v, false, lookup), s[len(v):]
+}
+
+// expandEnv replaces $NAME, ${NAME} and ${NAME:-default}
+// in s with values from lookup. If escapes is true,
+// backslash escapes are also replaced.
+func expandEnv(s string, escapes bool, lookup func(string) (string, bool)) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '\\' && escapes && i+1 < len(s):
+			i++
+			switch s[i] {
+			case 'n':
+				b.WriteByte('\n')
+			case 'r':
+				b.WriteByte('\r')
+			case 't':
+				b.WriteByte('\t')
+			default:
+				b.WriteByte(s[i])
+			}
+
+		case c == '$' && i+1 < len(s) && s[i+1] == '{':
+			end := strings.IndexByte(s[i:], '}')
+			if end < 0 {
+				b.WriteString(s[i:])
+				return b.String()
+			}
+			name, def, hasDef := strings.Cut(s[i+2:i+end], ":-")
+			if v, ok := lookup(name); ok && (v != "" || !hasDef) {
+				b.WriteString(v)
+			} else {
+				b.WriteString(def)
+			}
+			i += end
+
+		case c == '$':
+			j := i + 1
+			for j < len(s) && isEnvNameByte(s[j], j == i+1) {
+				j++
+			}
+			if j == i+1 {
+				b.WriteByte(c)
+				continue
+			}
+			v, _ := lookup(s[i+1 : j])
+			b.WriteString(v)
+			i = j - 1
+
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+func (dotenvSyntax) format(prefix, value string) (string, bool) {
+	plain := value != ""
+	for i := 0; i < len(value) && plain; i++ {
+		plain = isEnvNameByte(value[i], false) || strings.IndexByte("./:-+,@%", value[i]) >= 0
+	}
+	if plain {
+		return prefix + value, true
+	}
+
+	var b strings.Builder
+	b.WriteString(prefix)
+	b.WriteByte('"')
+	for i := 0; i < len(value); i++ {
+		switch c := value[i]; c {
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+			b.WriteString(`\r`)
+		case '\t':
+			b.WriteString(`\t`)
+		case '"', '\\', '$':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	b.WriteByte('"')
+	return b.String(), true
+}
+
+func (dotenvSyntax) sections() bool               { return false }
+func (dotenvSyntax) header(section string) string { return "" }
+
+func (dotenvSyntax) prefix(section, key string) string {
+	if !isEnvName(key) {
+		return ""
+	}
+	return key + "="
+}
+
+// isEnvName reports whether s is a valid variable name.
+func isEnvName(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if !isEnvNameByte(s[i], i == 0) {
+			return false
+		}
+	}
+	return true
+}
+
+// isEnvNameByte reports whether c may appear in a
+// variable name, at its start if first is true.
+func isEnvNameByte(c byte, first bool) bool {
+	switch {
+	case c == '_', 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z':
+		return true
+	case '0' <= c && c <= '9':
+		return !first
+	}
+	return false
+}
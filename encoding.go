@@ -0,0 +1,392 @@
+package basicfile
+
+import (
+	"fmt"
+	"io"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// Encoding is the character encoding of a text file.
+// Text is always decoded to UTF-8 when it is read and
+// encoded again when it is written.
+type Encoding int
+
+// Encodings supported by TextFile.
+const (
+	EncodingUTF8 Encoding = iota
+	EncodingUTF16LE
+	EncodingUTF16BE
+	EncodingLatin1 // ISO-8859-1
+	EncodingWindows1252
+)
+
+// ErrEncoding is matched by errors for text that is
+// not valid in its encoding. The error chain contains
+// an *EncodingError with the offset of the bad data.
+var ErrEncoding = NewGoFileError("invalid encoding", "", ErrInvalid)
+
+// EncodingError records the offset of the first byte
+// of text that is not valid in its encoding. When
+// decoding, Offset is a byte offset in the file; when
+// encoding, it is a byte offset in the UTF-8 text.
+type EncodingError struct {
+	Encoding Encoding
+	Offset   int64
+}
+
+func (e *EncodingError) Error() string {
+	return fmt.Sprintf("invalid %v at byte offset %d", e.Encoding, e.Offset)
+}
+
+// Unwrap returns ErrEncoding.
+func (e *EncodingError) Unwrap() error {
+	return ErrEncoding
+}
+
+func (e Encoding) String() string {
+	switch e {
+	case EncodingUTF8:
+		return "UTF-8"
+	case EncodingUTF16LE:
+		return "UTF-16LE"
+	case EncodingUTF16BE:
+		return "UTF-16BE"
+	case EncodingLatin1:
+		return "ISO-8859-1"
+	case EncodingWindows1252:
+		return "Windows-1252"
+	}
+	return fmt.Sprintf("Encoding(%d)", int(e))
+}
+
+// valid reports whether e is a supported encoding.
+func (e Encoding) valid() bool {
+	return e >= EncodingUTF8 && e <= EncodingWindows1252
+}
+
+// unit returns the size in bytes of a code unit of e.
+func (e Encoding) unit() int {
+	if e == EncodingUTF16LE || e == EncodingUTF16BE {
+		return 2
+	}
+	return 1
+}
+
+// bom returns the byte order mark of e, or NoBOM if e
+// has none.
+func (e Encoding) bom() BOM {
+	switch e {
+	case EncodingUTF8:
+		return BOMUTF8
+	case EncodingUTF16LE:
+		return BOMUTF16LE
+	case EncodingUTF16BE:
+		return BOMUTF16BE
+	}
+	return NoBOM
+}
+
+// encodingOf returns the encoding indicated by bom.
+func encodingOf(bom BOM) Encoding {
+	switch bom {
+	case BOMUTF16LE:
+		return EncodingUTF16LE
+	case BOMUTF16BE:
+		return EncodingUTF16BE
+	}
+	return EncodingUTF8
+}
+
+// sniffEncoding returns the encoding of b, the start of
+// a file without a byte order mark. If atEOF is false,
+// b is only the start of the file.
+//
+// Text with NUL bytes in at least two and at least half
+// of its code units, nearly all in the same byte of the
+// unit, is UTF-16 in the byte order that puts them
+// there: mostly-ASCII UTF-16 has a NUL high byte in
+// every character. Other text is UTF-8 if it is valid
+// UTF-8, and otherwise legacyEncoding(b): Windows-1252,
+// or Latin-1 if b has a byte Windows-1252 does not
+// define.
+func sniffEncoding(b []byte, atEOF bool) Encoding {
+	if e, ok := sniffUTF16(b); ok {
+		return e
+	}
+	if _, ok := validUTF8(b, atEOF); ok {
+		return EncodingUTF8
+	}
+	return legacyEncoding(b)
+}
+
+// sniffUTF16 returns the byte order of b if it looks like
+// UTF-16 text, as described for sniffEncoding.
+func sniffUTF16(b []byte) (Encoding, bool) {
+	units := len(b) / 2
+	if units < 2 {
+		return 0, false
+	}
+	var lo, hi int // NULs in the first and second byte of a unit
+	for i := 0; i+1 < len(b); i += 2 {
+		if b[i] == 0 {
+			lo++
+		}
+		if b[i+1] == 0 {
+			hi++
+		}
+	}
+	switch {
+	case hi >= 2 && 2*hi >= units && 10*lo <= hi:
+		return EncodingUTF16LE, true
+	case lo >= 2 && 2*lo >= units && 10*hi <= lo:
+		return EncodingUTF16BE, true
+	}
+	return 0, false
+}
+
+// legacyEncoding returns the single-byte encoding of b,
+// text that is not UTF-8: Windows-1252, or Latin-1 if b
+// contains a byte that Windows-1252 does not define.
+func legacyEncoding(b []byte) Encoding {
+	for _, c := range b {
+		if c >= 0x80 && c < 0xA0 && cp1252[c-0x80] == rune(c) {
+			return EncodingLatin1
+		}
+	}
+	return EncodingWindows1252
+}
+
+// cp1252 maps bytes 0x80 through 0x9F of Windows-1252 to
+// runes. Bytes that are undefined in Windows-1252 map to
+// the C1 control with the same value, as browsers do.
+var cp1252 = [32]rune{
+	0x20AC, 0x0081, 0x201A, 0x0192, 0x201E, 0x2026, 0x2020, 0x2021,
+	0x02C6, 0x2030, 0x0160, 0x2039, 0x0152, 0x008D, 0x017D, 0x008F,
+	0x0090, 0x2018, 0x2019, 0x201C, 0x201D, 0x2022, 0x2013, 0x2014,
+	0x02DC, 0x2122, 0x0161, 0x203A, 0x0153, 0x009D, 0x017E, 0x0178,
+}
+
+// decode returns b, text in encoding e, as a UTF-8
+// string. If atEOF is false, b may end part way through
+// a character, which is dropped.
+//
+// If b is not valid, the error is an *EncodingError
+// with the offset of the bad data in b.
+func decode(b []byte, e Encoding, atEOF bool) (string, error) {
+	s, _, err := decodePrefix(b, e, atEOF)
+	return s, err
+}
+
+// decodePrefix is decode, but also returns the number
+// of bytes of b that were decoded.
+func decodePrefix(b []byte, e Encoding, atEOF bool) (string, int, error) {
+	switch e {
+	case EncodingUTF8:
+		n, ok := validUTF8(b, atEOF)
+		if !ok {
+			return "", 0, &EncodingError{Encoding: e, Offset: int64(n)}
+		}
+		return string(b[:n]), n, nil
+
+	case EncodingUTF16LE, EncodingUTF16BE:
+		return decodeUTF16(b, e, atEOF)
+
+	case EncodingLatin1, EncodingWindows1252:
+		buf := make([]byte, 0, len(b)+len(b)/8)
+		for _, c := range b {
+			r := rune(c)
+			if e == EncodingWindows1252 && c >= 0x80 && c < 0xA0 {
+				r = cp1252[c-0x80]
+			}
+			buf = utf8.AppendRune(buf, r)
+		}
+		return string(buf), len(b), nil
+	}
+	return "", 0, &EncodingError{Encoding: e}
+}
+
+// validUTF8 returns the length of the valid UTF-8 prefix
+// of b and whether all of b is valid. If atEOF is false,
+// an incomplete character at the end of b is not an
+// error and is excluded from the length.
+func validUTF8(b []byte, atEOF bool) (int, bool) {
+	if utf8.Valid(b) {
+		return len(b), true
+	}
+	for i := 0; i < len(b); {
+		r, size := utf8.DecodeRune(b[i:])
+		if r == utf8.RuneError && size == 1 {
+			if !atEOF && !utf8.FullRune(b[i:]) {
+				return i, true
+			}
+			return i, false
+		}
+		i += size
+	}
+	return len(b), true
+}
+
+// decodeUTF16 decodes b, UTF-16 text in byte order e.
+func decodeUTF16(b []byte, e Encoding, atEOF bool) (string, int, error) {
+	bad := func(off int) (string, int, error) {
+		return "", 0, &EncodingError{Encoding: e, Offset: int64(off)}
+	}
+
+	buf := make([]byte, 0, len(b))
+	i := 0
+	for ; i < len(b); i += 2 {
+		if i+1 >= len(b) {
+			if atEOF {
+				return bad(i)
+			}
+			break
+		}
+		r := rune(utf16Unit(b[i:], e))
+		if utf16.IsSurrogate(r) {
+			if i+3 >= len(b) && !atEOF {
+				break
+			}
+			if r >= 0xDC00 || i+3 >= len(b) {
+				return bad(i)
+			}
+			r = utf16.DecodeRune(r, rune(utf16Unit(b[i+2:], e)))
+			if r == utf8.RuneError {
+				return bad(i)
+			}
+			i += 2
+		}
+		buf = utf8.AppendRune(buf, r)
+	}
+	return string(buf), i, nil
+}
+
+// utf16Unit returns the code unit at the start of b.
+func utf16Unit(b []byte, e Encoding) uint16 {
+	if e == EncodingUTF16BE {
+		return uint16(b[0])<<8 | uint16(b[1])
+	}
+	return uint16(b[1])<<8 | uint16(b[0])
+}
+
+// encode returns s, UTF-8 text, in encoding e.
+//
+// If s contains a character that cannot be represented
+// in e, the error is an *EncodingError with the offset
+// of the character in s.
+func encode(s string, e Encoding) ([]byte, error) {
+	switch e {
+	case EncodingUTF8:
+		n, ok := validUTF8([]byte(s), true)
+		if !ok {
+			return nil, &EncodingError{Encoding: e, Offset: int64(n)}
+		}
+		return []byte(s), nil
+
+	case EncodingUTF16LE, EncodingUTF16BE:
+		buf := make([]byte, 0, 2*len(s))
+		put := func(u uint16) {
+			if e == EncodingUTF16BE {
+				buf = append(buf, byte(u>>8), byte(u))
+			} else {
+				buf = append(buf, byte(u), byte(u>>8))
+			}
+		}
+		for _, r := range s {
+			if r1, r2 := utf16.EncodeRune(r); r1 != utf8.RuneError {
+				put(uint16(r1))
+				put(uint16(r2))
+			} else {
+				put(uint16(r))
+			}
+		}
+		return buf, nil
+
+	case EncodingLatin1, EncodingWindows1252:
+		buf := make([]byte, 0, len(s))
+		for i, r := range s {
+			c, ok := encodeByte(r, e)
+			if !ok {
+				return nil, &EncodingError{Encoding: e, Offset: int64(i)}
+			}
+			buf = append(buf, c)
+		}
+		return buf, nil
+	}
+	return nil, &EncodingError{Encoding: e}
+}
+
+// encodeByte returns r in the single byte encoding e.
+func encodeByte(r rune, e Encoding) (byte, bool) {
+	if e == EncodingWindows1252 {
+		for i, c := range cp1252 {
+			if c == r {
+				return byte(0x80 + i), true
+			}
+		}
+		if r >= 0x80 && r < 0xA0 {
+			return 0, false
+		}
+	}
+	if r < 0x100 {
+		return byte(r), true
+	}
+	return 0, false
+}
+
+// decodeReader decodes text in an encoding to UTF-8 as
+// it is read from r.
+type decodeReader struct {
+	r   io.Reader
+	enc Encoding
+	off int64  // offset in r of the start of raw
+	raw []byte // undecoded input
+	out []byte // decoded output not yet read
+	err error
+}
+
+// newDecodeReader returns a reader that decodes r from
+// encoding e. off is the offset of r in the file, used
+// to report the offsets of invalid text.
+func newDecodeReader(r io.Reader, e Encoding, off int64) *decodeReader {
+	return &decodeReader{r: r, enc: e, off: off}
+}
+
+func (d *decodeReader) Read(p []byte) (int, error) {
+	for len(d.out) == 0 {
+		if d.err != nil {
+			return 0, d.err
+		}
+		d.fill()
+	}
+	n := copy(p, d.out)
+	d.out = d.out[n:]
+	return n, nil
+}
+
+// fill reads and decodes the next chunk of input.
+func (d *decodeReader) fill() {
+	if d.raw == nil {
+		d.raw = make([]byte, 0, defaultBufSize)
+	}
+	n, err := d.r.Read(d.raw[len(d.raw):cap(d.raw)])
+	d.raw = d.raw[:len(d.raw)+n]
+	if err != nil && err != io.EOF {
+		d.err = err
+		return
+	}
+	atEOF := err == io.EOF
+
+	s, used, derr := decodePrefix(d.raw, d.enc, atEOF)
+	if derr != nil {
+		derr.(*EncodingError).Offset += d.off
+		d.err = derr
+		return
+	}
+	d.out = append(d.out[:0], s...)
+	d.off += int64(used)
+	d.raw = d.raw[:copy(d.raw, d.raw[used:])]
+	if atEOF {
+		d.err = io.EOF
+	}
+}
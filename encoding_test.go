@@ -0,0 +1,191 @@
+package basicfile
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestDecodeEncode(t *testing.T) {
+	tests := []struct {
+		enc  Encoding
+		raw  string
+		text string
+	}{
+		{EncodingUTF8, "h\xC3\xA9", "hé"},
+		{EncodingUTF16LE, "h\x00\xE9\x00=\xD8\x00\xDE", "hé😀"},
+		{EncodingUTF16BE, "\x00h\x00\xE9\xD8=\xDE\x00", "hé😀"},
+		{EncodingLatin1, "h\xE9\x80", "hé\u0080"},
+		{EncodingWindows1252, "h\xE9\x80\x81", "hé€\u0081"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.enc.String(), func(t *testing.T) {
+			got, err := decode([]byte(tt.raw), tt.enc, true)
+			if err != nil || got != tt.text {
+				t.Errorf("decode() = %q, %v, want %q", got, err, tt.text)
+			}
+			b, err := encode(tt.text, tt.enc)
+			if err != nil || string(b) != tt.raw {
+				t.Errorf("encode() = %q, %v, want %q", b, err, tt.raw)
+			}
+		})
+	}
+}
+
+func TestDecodeInvalid(t *testing.T) {
+	tests := []struct {
+		enc    Encoding
+		raw    string
+		offset int64
+	}{
+		{EncodingUTF8, "ab\xC3(", 2},
+		{EncodingUTF16LE, "a\x00\x00\xDC", 2},
+		{EncodingUTF16LE, "a\x00b", 2},
+	}
+	for _, tt := range tests {
+		_, err := decode([]byte(tt.raw), tt.enc, true)
+		var e *EncodingError
+		if !errors.As(err, &e) || e.Offset != tt.offset {
+			t.Errorf("decode(%q, %v) = %v, want offset %d", tt.raw, tt.enc, err, tt.offset)
+		}
+	}
+
+	// An incomplete character is not an error
+	// before the end of the data.
+	if s, err := decode([]byte("ab\xC3"), EncodingUTF8, false); err != nil || s != "ab" {
+		t.Errorf("decode() of partial data = %q, %v, want %q", s, err, "ab")
+	}
+
+	if _, err := encode("€", EncodingLatin1); err == nil {
+		t.Error("encode(€, Latin-1) succeeded, want error")
+	}
+}
+
+func TestTextFileInvalidUTF8(t *testing.T) {
+	path := writeTestFile(t, "bad.txt", "good\nba\xFFd \x80\n")
+
+	// Text that is not UTF-8 falls back to Windows-1252,
+	f, err := OpenText(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.Encoding() != EncodingWindows1252 || f.Text() != "good\nbaÿd €\n" {
+		t.Errorf("Encoding() = %v, Text() = %q", f.Encoding(), f.Text())
+	}
+	// and to Latin-1 for bytes Windows-1252 does not define.
+	f, err = OpenText(writeTestFile(t, "latin1.txt", "a\x81\xE9\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.Encoding() != EncodingLatin1 || f.Text() != "a\u0081é\n" {
+		t.Errorf("Encoding() = %v, Text() = %q", f.Encoding(), f.Text())
+	}
+
+	// A UTF-8 file is only checked when it is read; a
+	// streamed file is sniffed from its first
+	// defaultBufSize bytes.
+	long := strings.Repeat("x", defaultBufSize)
+	f, err = OpenText(writeTestFile(t, "long.txt", long+"\nba\xFFd\n"), WithStreaming())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var e *EncodingError
+	err = f.ScanLines(func(int, []byte) error { return nil })
+	if want := int64(len(long) + 3); !errors.As(err, &e) || e.Offset != want {
+		t.Errorf("ScanLines() = %v, want *EncodingError at offset %d", err, want)
+	}
+
+	if _, ok := err.(*GoFileError); !ok || !errors.Is(err, ErrEncoding) {
+		t.Errorf("ScanLines() error %T does not match ErrEncoding", err)
+	}
+
+	f, err = OpenText(path, WithEncoding(EncodingLatin1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := f.Text(); got != "good\nbaÿd \u0080\n" {
+		t.Errorf("Text() as Latin-1 = %q", got)
+	}
+}
+
+func TestTextFileSniffUTF16(t *testing.T) {
+	for _, tt := range []struct {
+		raw  string
+		want Encoding
+	}{
+		{"h\x00i\x00\n\x00\xE9\x00\n\x00", EncodingUTF16LE},
+		{"\x00h\x00i\x00\n\x00\xE9\x00\n", EncodingUTF16BE},
+		{"hi\x00\n", EncodingUTF8},
+		{"hello\x00world\x00\n", EncodingUTF8},
+	} {
+		path := writeTestFile(t, "utf16.txt", tt.raw)
+		for _, opts := range [][]TextOption{nil, {WithStreaming()}} {
+			f, err := OpenText(path, opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := f.Encoding(); got != tt.want {
+				t.Errorf("Encoding() of %q = %v, want %v", tt.raw, got, tt.want)
+			}
+		}
+		if tt.want == EncodingUTF8 {
+			continue
+		}
+		f, _ := OpenText(path)
+		if got, _ := f.Lines(); len(got) != 2 || got[1] != "é" {
+			t.Errorf("Lines() of %q = %q", tt.raw, got)
+		}
+		if err := f.AppendLine("x"); err != nil {
+			t.Fatal(err)
+		}
+		if err := f.Save(); err != nil {
+			t.Fatal(err)
+		}
+		b, _ := os.ReadFile(path)
+		if want, _ := encode("hi\né\nx\n", tt.want); string(b) != string(want) {
+			t.Errorf("saved %v = %q, want %q", tt.want, b, want)
+		}
+	}
+}
+
+func TestSetEncoding(t *testing.T) {
+	path := writeTestFile(t, "convert.txt", "café\n")
+
+	f, err := OpenText(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := f.SetEncoding(EncodingUTF16LE); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Save(); err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := os.ReadFile(path); string(b) != "\xFF\xFEc\x00a\x00f\x00\xE9\x00\n\x00" {
+		t.Errorf("saved UTF-16LE = %q", b)
+	}
+
+	f, err = OpenText(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.Encoding() != EncodingUTF16LE || f.Text() != "café\n" {
+		t.Errorf("reopened: Encoding() = %v, Text() = %q", f.Encoding(), f.Text())
+	}
+	if err := f.SetEncoding(Encoding(99)); err == nil {
+		t.Error("SetEncoding(99) succeeded, want error")
+	}
+	if err := f.SetEncoding(EncodingWindows1252); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.AppendLine("5 €"); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Save(); err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := os.ReadFile(path); string(b) != "caf\xE9\n5 \x80\n" {
+		t.Errorf("saved Windows-1252 = %q", b)
+	}
+}
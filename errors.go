@@ -53,6 +53,7 @@ var (
 	ErrNoAlloc          = NewGoFileError("memory allocation failure", "", ErrInvalid)
 	ErrNotImplemented   = NewGoFileError("feature not implemented", "", ErrInvalid)
 	ErrFileLocked       = NewGoFileError("file locked", "", ErrClosed)
+	ErrChecksum         = NewGoFileError("checksum mismatch", "", ErrInvalid)
 	ErrExist            = NewGoFileError("", "", fs.ErrExist)
 	ErrNotExist         = NewGoFileError("", "", fs.ErrNotExist)
 	ErrPermission       = NewGoFileError("", "", fs.ErrPermission)
@@ -46,12 +46,33 @@ func (f *basicFile) FileInfo() fs.FileInfo {
 //
 // Errors are logged if Err is active.
 func (f *basicFile) Stat() (fs.FileInfo, error) {
+	f.mu.RLock()
+	fi := f.fi
+	if f.isDirty {
+		fi = nil
+	}
+	f.mu.RUnlock()
+	if fi != nil {
+		return fi, nil
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.statLocked()
+}
+
+// statLocked refreshes the cached FileInfo if it is
+// missing or dirty. The caller must hold f.mu
+// exclusively.
+func (f *basicFile) statLocked() (fs.FileInfo, error) {
 	if f.fi == nil || f.isDirty {
-		fi, err := Stat(f.Name())
+		fi, err := Stat(f.providedName)
 		if Err(err) != nil {
 			return nil, err
 		}
 		f.fi = fi
+		f.mode = fi.Mode()
+		f.isDirty = false
 	}
 	return f.fi, nil
 }
@@ -20,10 +20,11 @@ import (
 //
 // Reference: standard library fs.go
 func (f *basicFile) FileMode() os.FileMode {
-	if f.mode == 0 {
-		f.mode = f.FileInfo().Mode()
+	fi := f.FileInfo()
+	if fi == nil {
+		return 0
 	}
-	return f.mode
+	return fi.Mode()
 }
 
 // FileMode returns the filemode of file.
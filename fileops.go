@@ -4,6 +4,8 @@ import (
 	"bytes"
 	"errors"
 	"os"
+	"syscall"
+	"time"
 )
 
 const (
@@ -27,3 +29,126 @@ func NotExists(filename string) bool {
 	_, err := os.Stat(filename)
 	return errors.Is(err, os.ErrNotExist)
 }
+
+// Chmod changes the mode of the file to mode.
+func (f *basicFile) Chmod(mode os.FileMode) error {
+	ff, err := f.rlockFile()
+	if err != nil {
+		return err
+	}
+	err = ff.Chmod(mode)
+	f.mu.RUnlock()
+	f.Dirty()
+	return err
+}
+
+// Chown changes the numeric uid and gid of the file.
+func (f *basicFile) Chown(uid, gid int) error {
+	ff, err := f.rlockFile()
+	if err != nil {
+		return err
+	}
+	err = ff.Chown(uid, gid)
+	f.mu.RUnlock()
+	f.Dirty()
+	return err
+}
+
+// Sync commits the current contents of the file
+// to stable storage.
+func (f *basicFile) Sync() error {
+	ff, err := f.rlockFile()
+	if err != nil {
+		return err
+	}
+	defer f.mu.RUnlock()
+	return ff.Sync()
+}
+
+// Chdir changes the current working directory to the
+// file, which must be a directory.
+func (f *basicFile) Chdir() error {
+	ff, err := f.rlockFile()
+	if err != nil {
+		return err
+	}
+	defer f.mu.RUnlock()
+	return ff.Chdir()
+}
+
+// ReadDir reads the contents of the directory and
+// returns up to n entries, as os.File.ReadDir does.
+func (f *basicFile) ReadDir(n int) ([]os.DirEntry, error) {
+	ff, err := f.rlockFile()
+	if err != nil {
+		return nil, err
+	}
+	defer f.mu.RUnlock()
+	return ff.ReadDir(n)
+}
+
+// Readdir reads the contents of the directory and
+// returns up to n FileInfo values, as os.File.Readdir
+// does.
+func (f *basicFile) Readdir(n int) ([]os.FileInfo, error) {
+	ff, err := f.rlockFile()
+	if err != nil {
+		return nil, err
+	}
+	defer f.mu.RUnlock()
+	return ff.Readdir(n)
+}
+
+// Readdirnames reads the contents of the directory and
+// returns up to n names, as os.File.Readdirnames does.
+func (f *basicFile) Readdirnames(n int) ([]string, error) {
+	ff, err := f.rlockFile()
+	if err != nil {
+		return nil, err
+	}
+	defer f.mu.RUnlock()
+	return ff.Readdirnames(n)
+}
+
+// SetDeadline sets the read and write deadlines
+// for the file, if it supports them.
+func (f *basicFile) SetDeadline(t time.Time) error {
+	ff, err := f.rlockFile()
+	if err != nil {
+		return err
+	}
+	defer f.mu.RUnlock()
+	return ff.SetDeadline(t)
+}
+
+// SetReadDeadline sets the deadline for future
+// Read calls, if the file supports it.
+func (f *basicFile) SetReadDeadline(t time.Time) error {
+	ff, err := f.rlockFile()
+	if err != nil {
+		return err
+	}
+	defer f.mu.RUnlock()
+	return ff.SetReadDeadline(t)
+}
+
+// SetWriteDeadline sets the deadline for future
+// Write calls, if the file supports it.
+func (f *basicFile) SetWriteDeadline(t time.Time) error {
+	ff, err := f.rlockFile()
+	if err != nil {
+		return err
+	}
+	defer f.mu.RUnlock()
+	return ff.SetWriteDeadline(t)
+}
+
+// SyscallConn returns a raw file.
+func (f *basicFile) SyscallConn() (syscall.RawConn, error) {
+	ff, err := f.rlockFile()
+	if err != nil {
+		return nil, err
+	}
+	defer f.mu.RUnlock()
+	return ff.SyscallConn()
+}
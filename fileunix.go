@@ -3,3 +3,19 @@ package basicfile
 func (f *basicFile) FileUnix() FileUnix {
 	return f
 }
+
+// Fd returns the integer Unix file descriptor
+// referencing the open file, opening it if needed.
+// If the file cannot be opened, Fd returns
+// ^uintptr(0).
+//
+// The descriptor is only valid until the file is
+// closed by Close or Flush.
+func (f *basicFile) Fd() uintptr {
+	ff, err := f.rlockFile()
+	if err != nil {
+		return ^uintptr(0)
+	}
+	defer f.mu.RUnlock()
+	return ff.Fd()
+}
@@ -0,0 +1,589 @@
+package basicfile
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+)
+
+// TrimMode controls which padding is removed from the
+// values of a FixedColumn when they are read.
+type TrimMode int
+
+// Trim modes. TrimDefault trims both ends.
+const (
+	TrimDefault TrimMode = iota
+	TrimNone
+	TrimLeft
+	TrimRight
+	TrimBoth
+)
+
+// Alignment controls where a value is placed in a
+// FixedColumn when it is written.
+type Alignment int
+
+// Alignments. With AlignDefault, ColumnInt and
+// ColumnFloat values are aligned right and others left.
+const (
+	AlignDefault Alignment = iota
+	AlignLeft
+	AlignRight
+)
+
+// ErrFieldOverflow is matched by errors for values that
+// are wider than their fixed-width column.
+var ErrFieldOverflow = NewGoFileError("value too wide for field", "", ErrInvalid)
+
+type (
+	// FixedColumn describes a column of a FixedWidthFile:
+	// Width bytes at byte Offset in each record. Name,
+	// Type, Required and Layout are as for Column.
+	FixedColumn struct {
+		Column
+		Offset int
+		Width  int
+		Trim   TrimMode  // padding removed when reading
+		Align  Alignment // placement when writing
+		Pad    byte      // padding when writing; default ' '
+	}
+
+	// FixedWidthFile is a file of fixed-width records,
+	// such as a mainframe export. Every record has the
+	// same length, so record n is read directly, with
+	// ReadAt, without reading the records before it.
+	FixedWidthFile interface {
+		BasicFile
+
+		// Layout returns the columns of a record.
+		Layout() []FixedColumn
+
+		// RecordLen returns the length of a record in
+		// bytes, including its terminator.
+		RecordLen() int
+
+		// Len returns the number of records.
+		Len() (int, error)
+
+		// Record returns record n (1-based).
+		Record(n int) (FixedRecord, error)
+
+		// Records returns an iterator that streams the
+		// records of the file from disk.
+		Records() FixedRecordIterator
+
+		// Append adds records, each with a value for
+		// each column, to the end of the file.
+		Append(records ...[]string) error
+
+		// SetRecord replaces record n (1-based).
+		SetRecord(n int, values []string) error
+	}
+
+	// FixedRecordIterator streams the records of a
+	// FixedWidthFile.
+	FixedRecordIterator interface {
+		io.Closer
+
+		// Next advances to the next record. It returns
+		// false at the end of the file or on error.
+		Next() bool
+
+		// Record returns the current record.
+		Record() FixedRecord
+
+		// Err returns the first error other than io.EOF
+		// encountered by Next.
+		Err() error
+	}
+
+	// FixedRecord is a record of a FixedWidthFile.
+	FixedRecord struct {
+		Fields []string // trimmed values, in layout order
+		Num    int      // 1-based record number
+
+		d *fixedFile
+	}
+
+	// A FixedOption configures a FixedWidthFile.
+	FixedOption func(*fixedFile)
+
+	fixedFile struct {
+		basicFile
+		fmu           sync.Mutex // serializes writes
+		layout        []FixedColumn
+		cols          map[string]int
+		width         int    // record length without terminator
+		term          string // record terminator
+		termSet       bool   // term was given, not detected
+		widthSet      bool   // width was given, not computed
+		lineTerminate bool   // records are lines
+	}
+
+	fixedIter struct {
+		d    *fixedFile
+		f    *os.File
+		r    *bufio.Reader
+		buf  []byte
+		rec  FixedRecord
+		err  error
+		done bool
+	}
+)
+
+// WithRecordTerminator sets the bytes that end each
+// record, such as "\n", "\r\n" or "" for none. By
+// default, "\r\n" or "\n" is detected after the first
+// record, and otherwise records have no terminator if
+// the file size is a multiple of the record width.
+func WithRecordTerminator(term string) FixedOption {
+	return func(d *fixedFile) {
+		d.term = term
+		d.termSet = true
+	}
+}
+
+// WithRecordWidth sets the width of a record, without
+// its terminator. The default is the end of the last
+// column; bytes not in any column are written as
+// spaces.
+func WithRecordWidth(n int) FixedOption {
+	return func(d *fixedFile) {
+		d.width = n
+		d.widthSet = true
+	}
+}
+
+// OpenFixedWidth opens the named file as a FixedWidthFile
+// with records laid out as layout.
+//
+// Offsets and widths are in bytes. Columns may not
+// overlap. Values are not decoded; they are returned
+// as they are stored, less padding.
+//
+// If there is an error, it will be of type *GoFileError.
+func OpenFixedWidth(name string, layout []FixedColumn, opts ...FixedOption) (FixedWidthFile, error) {
+	const op = "gofile.OpenFixedWidth"
+	d := &fixedFile{
+		layout: append([]FixedColumn(nil), layout...),
+		cols:   make(map[string]int, len(layout)),
+		term:   "\n",
+	}
+	d.basicFile.init(name, WithReadOnly())
+	for _, opt := range opts {
+		opt(d)
+	}
+
+	end := 0
+	for i, c := range d.layout {
+		if c.Offset < 0 || c.Width <= 0 {
+			return nil, d.layoutError(op, c, "bad offset or width")
+		}
+		if _, ok := d.cols[c.Name]; ok {
+			return nil, d.layoutError(op, c, "duplicate column")
+		}
+		for _, o := range d.layout[:i] {
+			if c.Offset < o.Offset+o.Width && o.Offset < c.Offset+c.Width {
+				return nil, d.layoutError(op, c, fmt.Sprintf("overlaps column %q", o.Name))
+			}
+		}
+		if d.layout[i].Pad == 0 {
+			d.layout[i].Pad = ' '
+		}
+		d.cols[c.Name] = i
+		if c.Offset+c.Width > end {
+			end = c.Offset + c.Width
+		}
+	}
+	if !d.widthSet {
+		d.width = end
+	}
+	if d.width <= 0 || d.width < end {
+		return nil, NewGoFileError(op, name, ErrInvalid)
+	}
+
+	fi, err := d.basicFile.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if !d.termSet {
+		if err := d.detectTerminator(fi.Size()); err != nil {
+			return nil, err
+		}
+	}
+	d.lineTerminate = strings.HasSuffix(d.term, "\n")
+	return d, nil
+}
+
+func (d *fixedFile) layoutError(op string, c FixedColumn, msg string) error {
+	return Err(&GoFileError{
+		Op:   prependGoFilePrefix(op),
+		Path: d.providedName,
+		Err:  fmt.Errorf("column %q: %s: %w", c.Name, msg, ErrInvalid),
+	})
+}
+
+// detectTerminator sets the record terminator from the
+// bytes after the first record of a file of size bytes.
+func (d *fixedFile) detectTerminator(size int64) error {
+	b := make([]byte, 2)
+	n, err := d.ReadAt(b, int64(d.width))
+	if err != nil && err != io.EOF {
+		return Err(NewGoFileError("gofile.OpenFixedWidth", d.providedName, err))
+	}
+	switch b = b[:n]; {
+	case bytes.HasPrefix(b, []byte("\r\n")):
+		d.term = "\r\n"
+	case bytes.HasPrefix(b, []byte("\n")):
+		d.term = "\n"
+	case size > 0 && size%int64(d.width) == 0:
+		d.term = ""
+	}
+	return nil
+}
+
+func (d *fixedFile) Layout() []FixedColumn {
+	return append([]FixedColumn(nil), d.layout...)
+}
+
+func (d *fixedFile) RecordLen() int {
+	return d.width + len(d.term)
+}
+
+// Len returns the number of records, counting a last
+// record that has no terminator. If the file does not
+// hold a whole number of records, the error wraps
+// ErrInvalid.
+func (d *fixedFile) Len() (int, error) {
+	d.basicFile.Dirty()
+	fi, err := d.basicFile.Stat()
+	if err != nil {
+		return 0, err
+	}
+	size, recLen := fi.Size(), int64(d.RecordLen())
+	n := size / recLen
+	switch size % recLen {
+	case 0:
+	case int64(d.width):
+		n++
+	default:
+		return 0, Err(&GoFileError{
+			Op:   prependGoFilePrefix("gofile.Len"),
+			Path: d.providedName,
+			Err:  fmt.Errorf("size %d is not a multiple of the record length %d: %w", size, recLen, ErrInvalid),
+		})
+	}
+	return int(n), nil
+}
+
+// Record returns record n (1-based), read with ReadAt.
+func (d *fixedFile) Record(n int) (FixedRecord, error) {
+	const op = "gofile.Record"
+	if n < 1 {
+		return FixedRecord{}, NewGoFileError(op, d.providedName, ErrInvalid)
+	}
+
+	b := make([]byte, d.RecordLen())
+	c, err := d.ReadAt(b, int64(n-1)*int64(len(b)))
+	if err == io.EOF && c == d.width {
+		err = nil // last record without a terminator
+	}
+	if err != nil {
+		if err == io.EOF && c == 0 {
+			err = ErrNotExist
+		} else if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return FixedRecord{}, Err(NewGoFileError(op, d.providedName, err))
+	}
+	return d.parse(op, n, b[:c])
+}
+
+// parse returns the record numbered n stored in b.
+func (d *fixedFile) parse(op string, n int, b []byte) (FixedRecord, error) {
+	if len(b) > d.width && string(b[d.width:]) != d.term {
+		return FixedRecord{}, Err(&GoFileError{
+			Op:   prependGoFilePrefix(op),
+			Path: d.providedName,
+			Err:  fmt.Errorf("record %d: bad record terminator %q: %w", n, b[d.width:], ErrInvalid),
+		})
+	}
+
+	r := FixedRecord{Fields: make([]string, len(d.layout)), Num: n, d: d}
+	for i, c := range d.layout {
+		r.Fields[i] = c.trim(string(b[c.Offset : c.Offset+c.Width]))
+	}
+	return r, nil
+}
+
+// trim removes the padding of c from s.
+func (c FixedColumn) trim(s string) string {
+	cut := " "
+	if c.Pad != ' ' && c.Pad != '0' {
+		cut += string(c.Pad)
+	}
+	switch c.Trim {
+	case TrimNone:
+		return s
+	case TrimLeft:
+		return strings.TrimLeft(s, cut)
+	case TrimRight:
+		return strings.TrimRight(s, cut)
+	}
+	return strings.Trim(s, cut)
+}
+
+// format returns s padded to the width of c.
+func (c FixedColumn) format(s string) string {
+	pad := strings.Repeat(string(c.Pad), c.Width-len(s))
+	right := c.Align == AlignRight ||
+		c.Align == AlignDefault && (c.Type == ColumnInt || c.Type == ColumnFloat)
+	if right {
+		if c.Pad == '0' && strings.HasPrefix(s, "-") {
+			return "-" + pad + s[1:]
+		}
+		return pad + s
+	}
+	return s + pad
+}
+
+func (d *fixedFile) Records() FixedRecordIterator {
+	it := &fixedIter{d: d, buf: make([]byte, d.RecordLen())}
+	f, err := os.Open(d.providedName)
+	if err != nil {
+		it.err = Err(NewGoFileError("gofile.Records", d.providedName, err))
+		it.done = true
+		return it
+	}
+	it.f = f
+	it.r = bufio.NewReaderSize(f, defaultBufSize)
+	return it
+}
+
+func (it *fixedIter) Next() bool {
+	if it.done {
+		return false
+	}
+
+	n, err := io.ReadFull(it.r, it.buf)
+	if err == io.ErrUnexpectedEOF && n == it.d.width {
+		err = nil // last record without a terminator
+	}
+	if err != nil {
+		it.done = true
+		if err != io.EOF {
+			it.err = Err(NewGoFileError("gofile.Records", it.d.providedName, err))
+		}
+		return false
+	}
+
+	rec, err := it.d.parse("gofile.Records", it.rec.Num+1, it.buf[:n])
+	if err != nil {
+		it.done = true
+		it.err = err
+		return false
+	}
+	it.rec = rec
+	return true
+}
+
+func (it *fixedIter) Record() FixedRecord { return it.rec }
+func (it *fixedIter) Err() error          { return it.err }
+
+// Close closes the file. It is safe to call more
+// than once.
+func (it *fixedIter) Close() error {
+	it.done = true
+	if it.f == nil {
+		return nil
+	}
+	f := it.f
+	it.f = nil
+	return f.Close()
+}
+
+// Append adds records to the end of the file. Every
+// record is checked before any is written: each must
+// have a value for each column that fits its width, has
+// no record terminator and is valid for its type.
+// Otherwise, the error is a *GoFileError wrapping a
+// *FieldError with the number the record would have and
+// the column name; a value that is too wide wraps
+// ErrFieldOverflow.
+func (d *fixedFile) Append(records ...[]string) error {
+	const op = "gofile.Append"
+	d.fmu.Lock()
+	defer d.fmu.Unlock()
+
+	n, err := d.Len()
+	if err != nil {
+		return err
+	}
+	fi, err := d.basicFile.Stat()
+	if err != nil {
+		return err
+	}
+
+	var b []byte
+	if fi.Size()%int64(d.RecordLen()) != 0 {
+		b = append(b, d.term...) // terminate the last record
+	}
+	for i, values := range records {
+		rec, err := d.format(op, n+i+1, values)
+		if err != nil {
+			return err
+		}
+		b = append(b, rec...)
+		b = append(b, d.term...)
+	}
+
+	f, err := os.OpenFile(d.providedName, os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		return Err(NewGoFileError(op, d.providedName, err))
+	}
+	_, err = f.Write(b)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	d.basicFile.Dirty()
+	if err != nil {
+		return Err(NewGoFileError(op, d.providedName, err))
+	}
+	return nil
+}
+
+// SetRecord replaces record n (1-based), which must
+// exist, in place. values are checked as for Append.
+func (d *fixedFile) SetRecord(n int, values []string) error {
+	const op = "gofile.SetRecord"
+	d.fmu.Lock()
+	defer d.fmu.Unlock()
+
+	count, err := d.Len()
+	if err != nil {
+		return err
+	}
+	if n < 1 || n > count {
+		return NewGoFileError(op, d.providedName, ErrInvalid)
+	}
+	rec, err := d.format(op, n, values)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(d.providedName, os.O_WRONLY, 0)
+	if err != nil {
+		return Err(NewGoFileError(op, d.providedName, err))
+	}
+	_, err = f.WriteAt(rec, int64(n-1)*int64(d.RecordLen()))
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	d.basicFile.Dirty()
+	if err != nil {
+		return Err(NewGoFileError(op, d.providedName, err))
+	}
+	return nil
+}
+
+// format returns record n, holding values, without its
+// terminator.
+func (d *fixedFile) format(op string, n int, values []string) ([]byte, error) {
+	if len(values) != len(d.layout) {
+		return nil, Err(&GoFileError{
+			Op:   prependGoFilePrefix(op),
+			Path: d.providedName,
+			Err:  fmt.Errorf("record %d: %d values for %d columns: %w", n, len(values), len(d.layout), ErrInvalid),
+		})
+	}
+
+	b := bytes.Repeat([]byte{' '}, d.width)
+	for i, c := range d.layout {
+		v := values[i]
+		fe := &FieldError{Row: n, Column: c.Name}
+		if d.lineTerminate {
+			fe.Line = n
+		}
+		switch {
+		case len(v) > c.Width:
+			fe.Err = ErrFieldOverflow
+		case d.term != "" && strings.Contains(v, d.term),
+			strings.ContainsAny(v, "\r\n"):
+			fe.Err = ErrInvalid
+		default:
+			fe.Err = c.check(v)
+		}
+		if fe.Err != nil {
+			return nil, Err(fieldError(op, d.providedName, fe))
+		}
+		copy(b[c.Offset:], c.format(v))
+	}
+	return b, nil
+}
+
+// Field returns field i (0-based) of the record, or ""
+// if there is no such column.
+func (r FixedRecord) Field(i int) string {
+	if i < 0 || i >= len(r.Fields) {
+		return ""
+	}
+	return r.Fields[i]
+}
+
+// Get returns the value of the named column.
+func (r FixedRecord) Get(col string) (string, error) {
+	i, ok := r.column(col)
+	if !ok {
+		return "", r.fieldError("gofile.Get", col, ErrNotExist)
+	}
+	return r.Fields[i], nil
+}
+
+// Value returns the value of the named column parsed as
+// the type of the column. An empty value is nil, or an
+// error wrapping ErrMissingValue if the column is
+// required.
+func (r FixedRecord) Value(col string) (interface{}, error) {
+	const op = "gofile.Value"
+	i, ok := r.column(col)
+	if !ok {
+		return nil, r.fieldError(op, col, ErrNotExist)
+	}
+	c, s := r.d.layout[i], r.Fields[i]
+	if s == "" {
+		if c.Required {
+			return nil, r.fieldError(op, col, ErrMissingValue)
+		}
+		return nil, nil
+	}
+	v, err := c.parse(s)
+	if err != nil {
+		return nil, r.fieldError(op, col, err)
+	}
+	return v, nil
+}
+
+// column returns the index of the named column.
+func (r FixedRecord) column(col string) (int, bool) {
+	if r.d == nil {
+		return 0, false
+	}
+	i, ok := r.d.cols[col]
+	return i, ok
+}
+
+func (r FixedRecord) fieldError(op, col string, err error) error {
+	fe := &FieldError{Row: r.Num, Column: col, Err: err}
+	path := ""
+	if r.d != nil {
+		path = r.d.providedName
+		if r.d.lineTerminate {
+			fe.Line = r.Num
+		}
+	}
+	return fieldError(op, path, fe)
+}
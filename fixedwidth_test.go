@@ -0,0 +1,106 @@
+package basicfile
+
+import (
+	"errors"
+	"os"
+	"reflect"
+	"testing"
+)
+
+var testFixedLayout = []FixedColumn{
+	{Column: Column{Name: "id", Type: ColumnInt, Required: true}, Offset: 0, Width: 5, Pad: '0'},
+	{Column: Column{Name: "name"}, Offset: 5, Width: 8},
+	{Column: Column{Name: "amount", Type: ColumnFloat}, Offset: 14, Width: 7},
+}
+
+func TestFixedWidthFile(t *testing.T) {
+	path := writeTestFile(t, "export.dat", ""+
+		"00001Alice      12.50\r\n"+
+		"00002Bob         3.00\r\n"+
+		"00003Carol           \r\n")
+
+	f, err := OpenFixedWidth(path, testFixedLayout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.RecordLen() != 23 {
+		t.Errorf("RecordLen() = %d, want 23", f.RecordLen())
+	}
+	if n, err := f.Len(); n != 3 || err != nil {
+		t.Errorf("Len() = %d, %v, want 3", n, err)
+	}
+
+	r, err := f.Record(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"00002", "Bob", "3.00"}; !reflect.DeepEqual(r.Fields, want) {
+		t.Errorf("Record(2).Fields = %q, want %q", r.Fields, want)
+	}
+	if v, err := r.Value("id"); v != int64(2) || err != nil {
+		t.Errorf("Value(id) = %v, %v, want 2", v, err)
+	}
+	r, _ = f.Record(3)
+	if v, err := r.Value("amount"); v != nil || err != nil {
+		t.Errorf("Value(amount) of a blank field = %v, %v, want nil", v, err)
+	}
+	if _, err := f.Record(4); !errors.Is(err, ErrNotExist) {
+		t.Errorf("Record(4) = %v, want ErrNotExist", err)
+	}
+
+	if err := f.Append([]string{"4", "Dave", "-1.5"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.SetRecord(2, []string{"2", "Robert", "3"}); err != nil {
+		t.Fatal(err)
+	}
+
+	err = f.Append([]string{"5", "Eve", "0"}, []string{"6", "Maximilian", "1"})
+	var fe *FieldError
+	if !errors.Is(err, ErrFieldOverflow) || !errors.As(err, &fe) || fe.Row != 6 || fe.Column != "name" {
+		t.Errorf("Append(too wide) = %v, want ErrFieldOverflow at record 6, column name", err)
+	}
+	if err := f.Append([]string{"x", "Eve", "1"}); !errors.As(err, &fe) || fe.Column != "id" {
+		t.Errorf("Append(bad id) = %v, want a FieldError for id", err)
+	}
+
+	want := "" +
+		"00001Alice      12.50\r\n" +
+		"00002Robert         3\r\n" +
+		"00003Carol           \r\n" +
+		"00004Dave        -1.5\r\n"
+	if got, _ := os.ReadFile(path); string(got) != want {
+		t.Errorf("file =\n%q\nwant\n%q", got, want)
+	}
+
+	it := f.Records()
+	defer it.Close()
+	var names []string
+	for it.Next() {
+		names = append(names, it.Record().Field(1))
+	}
+	if it.Err() != nil || !reflect.DeepEqual(names, []string{"Alice", "Robert", "Carol", "Dave"}) {
+		t.Errorf("Records() = %q, %v", names, it.Err())
+	}
+}
+
+func TestFixedWidthFileNoTerminator(t *testing.T) {
+	path := writeTestFile(t, "packed.dat", "00001Alice      12.5000002Bob         3.00")
+
+	f, err := OpenFixedWidth(path, testFixedLayout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, err := f.Len(); n != 2 || err != nil {
+		t.Errorf("Len() = %d, %v, want 2", n, err)
+	}
+	r, err := f.Record(2)
+	if err != nil || r.Field(1) != "Bob" {
+		t.Errorf("Record(2) = %q, %v", r.Fields, err)
+	}
+
+	layout := append(testFixedLayout[:2:2], FixedColumn{Column: Column{Name: "x"}, Offset: 6, Width: 2})
+	if _, err := OpenFixedWidth(path, layout); !errors.Is(err, ErrInvalid) {
+		t.Errorf("OpenFixedWidth(overlapping columns) = %v, want ErrInvalid", err)
+	}
+}
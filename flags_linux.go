@@ -0,0 +1,8 @@
+//go:build linux
+
+package basicfile
+
+import "syscall"
+
+// oDirect bypasses the page cache.
+const oDirect = syscall.O_DIRECT
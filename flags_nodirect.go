@@ -0,0 +1,7 @@
+//go:build !linux
+
+package basicfile
+
+// oDirect is not supported on this platform and
+// is ignored.
+const oDirect = 0
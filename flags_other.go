@@ -0,0 +1,7 @@
+//go:build windows || plan9
+
+package basicfile
+
+// oNoFollow is not supported on this platform and
+// is ignored.
+const oNoFollow = 0
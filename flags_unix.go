@@ -0,0 +1,9 @@
+//go:build !windows && !plan9
+
+package basicfile
+
+import "syscall"
+
+// oNoFollow refuses to open a symbolic link in the
+// final element of the path.
+const oNoFollow = syscall.O_NOFOLLOW
@@ -5,9 +5,7 @@ go 1.18
 require (
 	github.com/pkg/errors v0.9.1
 	github.com/skeptycal/errorlogger v0.5.0
+	golang.org/x/sys v0.0.0-20220329152356-43be30ef3008
 )
 
-require (
-	github.com/sirupsen/logrus v1.8.1 // indirect
-	golang.org/x/sys v0.0.0-20220329152356-43be30ef3008 // indirect
-)
+require github.com/sirupsen/logrus v1.8.1 // indirect
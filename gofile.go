@@ -25,13 +25,19 @@ type Handle interface {
 
 // Dirty sets isDirty to true.
 func (f *basicFile) Dirty() {
+	f.mu.Lock()
 	f.isDirty = true
+	f.mu.Unlock()
 }
 
 // OsFile returns the underlying
-// open file descriptor (*os.File).
+// open file descriptor (*os.File),
+// opening the file if needed.
+//
+// The *os.File is only valid until the
+// file is closed by Close or Flush.
 func (f *basicFile) OsFile() *os.File {
-	return f.File
+	return f.file()
 }
 
 // Handle returns a file 'handle' that is
@@ -41,6 +47,14 @@ func (f *basicFile) OsFile() *os.File {
 //  io.StringWriter
 //  io.ReaderFrom
 //  io.WriterTo
+//
+// Each handle has its own position, starting at
+// the beginning of the file, and its own buffers.
+// Close flushes buffered writes and releases the
+// handle's reference to the underlying file.
+//
+// If the file cannot be opened, nil is returned
+// and the error is logged.
 func (f *basicFile) Handle() Handle {
 	return f.rwc()
 }
@@ -65,13 +79,39 @@ func (f *basicFile) Name() string {
 	return filepath.Base(f.Abs())
 }
 
+// Compile time checks that basicFile implements
+// the GoFile interface and its components.
+var (
+	_ GoFile      = (*basicFile)(nil)
+	_ BasicFile   = (*basicFile)(nil)
+	_ FileOps     = (*basicFile)(nil)
+	_ FileUnix    = (*basicFile)(nil)
+	_ fs.DirEntry = (*basicFile)(nil)
+	_ fs.FileInfo = (*basicFile)(nil)
+)
+
+// NewGoFile returns a GoFile for the named file. The
+// file is opened, as configured by opts, when it is
+// first used, so every method may be called whether
+// or not the file has been opened yet.
+//
+// The file must exist when it is first used unless
+// opts include os.O_CREATE.
+func NewGoFile(name string, opts ...Option) (GoFile, error) {
+	return openFile(name, append(opts, withLazy())...)
+}
+
 type (
+	// GoFile implements the most common
+	// file functionality in Go.
 	GoFile interface {
 		fsFile
 		osFile
 		fs.DirEntry
 		fs.FileInfo
-		fs.FileMode
+
+		// FileMode returns the file mode bits.
+		FileMode() fs.FileMode
 
 		// OsFile returns the file descriptor, *os.File.
 		OsFile() *os.File
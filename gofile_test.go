@@ -0,0 +1,53 @@
+package basicfile
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestNewGoFile(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "gofile.txt")
+	if err := os.WriteFile(name, []byte("hello"), NormalMode); err != nil {
+		t.Fatal(err)
+	}
+
+	// each method must work on a file that has not been opened
+	tests := []struct {
+		name string
+		fn   func(f GoFile) error
+	}{
+		{"Chmod", func(f GoFile) error { return f.FileOps().Chmod(0600) }},
+		{"Sync", func(f GoFile) error { return f.FileOps().Sync() }},
+		{"Truncate", func(f GoFile) error { return f.FileUnix().Truncate(2) }},
+		{"Seek", func(f GoFile) error { _, err := f.Seek(0, 0); return err }},
+		{"Read", func(f GoFile) error { _, err := f.Read(make([]byte, 1)); return err }},
+		{"Stat", func(f GoFile) error { _, err := f.Stat(); return err }},
+		{"Info", func(f GoFile) error { _, err := f.Info(); return err }},
+		{"SyscallConn", func(f GoFile) error { _, err := f.FileOps().SyscallConn(); return err }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := NewGoFile(name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+			if err := tt.fn(f); err != nil {
+				t.Errorf("%s() on unopened file = %v, want nil", tt.name, err)
+			}
+		})
+	}
+
+	f, _ := NewGoFile(name)
+	defer f.Close()
+	if f.FileUnix().Fd() == ^uintptr(0) {
+		t.Error("Fd() on unopened file = invalid descriptor")
+	}
+	if got := f.Name(); got != "gofile.txt" {
+		t.Errorf("Name() = %q, want %q", got, "gofile.txt")
+	}
+	if f.Size() != 2 || f.FileMode().Perm() != 0600 || f.IsDir() {
+		t.Errorf("FileInfo = size %d, mode %v; want 2, -rw-------", f.Size(), f.FileMode())
+	}
+}
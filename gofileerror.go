@@ -66,6 +66,18 @@ func prependGoFilePrefix(msg string) string {
 	return goFileErrorPrefix + msg
 }
 
+// rootCause returns the innermost error in the
+// chain of err.
+func rootCause(err error) error {
+	for {
+		u := errors.Unwrap(err)
+		if u == nil {
+			return err
+		}
+		err = u
+	}
+}
+
 // checkGoFilePath checks if the path is not
 // provided, and adds PWD() as a general path.
 func checkGoFilePath(path string) string {
@@ -130,7 +142,7 @@ func (e *GoFileError) WithMessage(msg string) *GoFileError {
 // method on err, if err's type contains an Unwrap
 // method. Otherwise, Unwrap returns nil.
 func (e *GoFileError) Unwrap() error {
-	return errors.Unwrap(e.Err)
+	return e.Err
 }
 
 // Is reports whether any error in err's chain
@@ -144,8 +156,15 @@ func (e *GoFileError) Unwrap() error {
 // it is equal to that target or if it implements
 // a method Is(error) bool such that Is(target)
 // returns true.
+//
+// The portable sentinel errors of this package
+// (e.g. ErrExist, ErrNotExist) match any error
+// whose chain contains the same underlying cause.
 func (e *GoFileError) Is(target error) bool {
-	return errors.Is(e, target)
+	if t, ok := target.(*GoFileError); ok && t.Op == goFileErrorPrefix {
+		target = rootCause(t.Err)
+	}
+	return errors.Is(e.Err, target)
 }
 
 // As finds the first error in err's chain that
@@ -173,7 +192,7 @@ func (e *GoFileError) Is(target error) bool {
 // to either a type that implements error, or to
 // any interface type.
 func (e *GoFileError) As(target any) bool {
-	return errors.As(e, target)
+	return errors.As(e.Err, target)
 }
 
 // Timeout reports whether this error represents
@@ -0,0 +1,235 @@
+package basicfile
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"sync/atomic"
+)
+
+type (
+	// fileRef counts the references to an open file.
+	// The file is closed when the last reference is
+	// released.
+	fileRef struct {
+		file *os.File
+		n    int32 // atomic
+	}
+
+	// handle implements Handle with its own position
+	// in the file. Reads and writes use ReadAt and
+	// WriteAt at that position, so handles of the same
+	// file do not disturb each other.
+	//
+	// A handle is not safe for concurrent use by
+	// multiple goroutines.
+	handle struct {
+		f      *basicFile
+		ref    *fileRef
+		append bool  // the file was opened O_APPEND
+		off    int64 // position of the unbuffered side
+		r      *bufio.Reader
+		w      *bufio.Writer
+		closed bool
+	}
+
+	// handleIO is the unbuffered side of a handle.
+	handleIO struct{ h *handle }
+)
+
+func (r *fileRef) acquire() { atomic.AddInt32(&r.n, 1) }
+
+// release drops a reference, closing the file if it
+// was the last one.
+func (r *fileRef) release() error {
+	if atomic.AddInt32(&r.n, -1) == 0 {
+		return r.file.Close()
+	}
+	return nil
+}
+
+// newHandle returns a new handle positioned at the
+// start of the file. The file is opened if needed;
+// if so, it is closed again when the last handle is
+// closed.
+func (f *basicFile) newHandle() (*handle, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.File == nil {
+		ff, err := os.OpenFile(f.providedName, f.cfg.reopenFlag(), f.cfg.perm)
+		if err != nil {
+			return nil, NewGoFileError("gofile.Handle", f.providedName, err)
+		}
+		f.attachLocked(ff, false)
+	}
+	f.ref.acquire()
+	f.isDirty = true
+
+	h := &handle{
+		f:      f,
+		ref:    f.ref,
+		append: f.cfg.reopenFlag()&os.O_APPEND != 0,
+	}
+	h.r = f.cfg.newReader(handleIO{h})
+	h.w = f.cfg.newWriter(handleIO{h})
+	return h, nil
+}
+
+// Read reads up to len(p) bytes at the position of
+// the handle. Buffered writes are flushed first.
+func (h *handle) Read(p []byte) (int, error) {
+	if err := h.reading(); err != nil {
+		return 0, err
+	}
+	return h.r.Read(p)
+}
+
+// WriteTo writes the remainder of the file, from the
+// position of the handle, to w.
+func (h *handle) WriteTo(w io.Writer) (int64, error) {
+	if err := h.reading(); err != nil {
+		return 0, err
+	}
+	return h.r.WriteTo(w)
+}
+
+// Write writes p at the position of the handle. The
+// data is buffered until the buffer is full, Flush or
+// Close is called, or the handle is read from.
+func (h *handle) Write(p []byte) (int, error) {
+	if err := h.writing(); err != nil {
+		return 0, err
+	}
+	return h.w.Write(p)
+}
+
+// WriteString is like Write, but writes the contents
+// of the string s.
+func (h *handle) WriteString(s string) (int, error) {
+	if err := h.writing(); err != nil {
+		return 0, err
+	}
+	return h.w.WriteString(s)
+}
+
+// ReadFrom writes the contents of r at the position
+// of the handle.
+func (h *handle) ReadFrom(r io.Reader) (int64, error) {
+	if err := h.writing(); err != nil {
+		return 0, err
+	}
+	return h.w.ReadFrom(r)
+}
+
+// Seek sets the position of the handle, interpreted
+// according to whence.
+func (h *handle) Seek(offset int64, whence int) (int64, error) {
+	if err := h.reading(); err != nil {
+		return 0, err
+	}
+
+	pos := h.off - int64(h.r.Buffered())
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += pos
+	case io.SeekEnd:
+		fi, err := h.ref.file.Stat()
+		if err != nil {
+			return 0, err
+		}
+		offset += fi.Size()
+	default:
+		return 0, NewGoFileError("gofile.Seek", h.f.providedName, ErrInvalid)
+	}
+	if offset < 0 {
+		return 0, NewGoFileError("gofile.Seek", h.f.providedName, ErrInvalid)
+	}
+
+	h.off = offset
+	h.r.Reset(handleIO{h})
+	return offset, nil
+}
+
+// Flush writes any buffered data to the file.
+func (h *handle) Flush() error {
+	if h.closed {
+		return ErrClosed
+	}
+	return h.w.Flush()
+}
+
+// Close flushes any buffered data and releases the
+// reference of the handle to the file. The file is
+// closed when no BasicFile or handle refers to it.
+func (h *handle) Close() error {
+	if h.closed {
+		return ErrClosed
+	}
+	err := h.w.Flush()
+	h.closed = true
+
+	f := h.f
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.ref == h.ref && !f.owned && atomic.LoadInt32(&h.ref.n) == 1 {
+		// last reference to a file opened for handles
+		if f.processLocked() {
+			// closing would release the locks; the
+			// BasicFile takes over the reference.
+			f.owned = true
+			return err
+		}
+		f.File = nil
+		f.ref = nil
+		f.fi = nil
+	}
+	if rerr := h.ref.release(); err == nil {
+		err = rerr
+	}
+	f.isDirty = true
+	return err
+}
+
+// reading prepares the handle for reading by writing
+// any buffered data.
+func (h *handle) reading() error {
+	if h.closed {
+		return ErrClosed
+	}
+	return h.w.Flush()
+}
+
+// writing prepares the handle for writing by moving
+// the position back over data that has been read
+// ahead but not returned.
+func (h *handle) writing() error {
+	if h.closed {
+		return ErrClosed
+	}
+	if n := h.r.Buffered(); n > 0 {
+		h.off -= int64(n)
+		h.r.Reset(handleIO{h})
+	}
+	return nil
+}
+
+func (u handleIO) Read(p []byte) (int, error) {
+	n, err := u.h.ref.file.ReadAt(p, u.h.off)
+	u.h.off += int64(n)
+	if err == io.EOF && n > 0 {
+		err = nil
+	}
+	return n, err
+}
+
+func (u handleIO) Write(p []byte) (int, error) {
+	if u.h.append {
+		return u.h.ref.file.Write(p)
+	}
+	n, err := u.h.ref.file.WriteAt(p, u.h.off)
+	u.h.off += int64(n)
+	return n, err
+}
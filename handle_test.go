@@ -0,0 +1,63 @@
+package basicfile
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestHandle(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "handles.txt")
+	if err := os.WriteFile(name, []byte("0123456789"), NormalMode); err != nil {
+		t.Fatal(err)
+	}
+	bf, _ := NewGoFile(name)
+	f := bf.(*basicFile)
+
+	a, b := f.Handle(), f.Handle()
+	if a == nil || b == nil {
+		t.Fatal("Handle() = nil")
+	}
+
+	buf := make([]byte, 4)
+	if _, err := io.ReadFull(a, buf); err != nil || string(buf) != "0123" {
+		t.Errorf("a.Read() = %q, %v; want %q", buf, err, "0123")
+	}
+	// b has its own position
+	if _, err := io.ReadFull(b, buf); err != nil || string(buf) != "0123" {
+		t.Errorf("b.Read() = %q, %v; want %q", buf, err, "0123")
+	}
+
+	// a writes at its position, after the data it has
+	// returned, not after what it has buffered.
+	if _, err := a.WriteString("ab"); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := os.ReadFile(name); string(got) != "0123456789" {
+		t.Errorf("file before Close() = %q, want unchanged", got)
+	}
+	if err := a.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := os.ReadFile(name); string(got) != "0123ab6789" {
+		t.Errorf("file after Close() = %q, want %q", got, "0123ab6789")
+	}
+	if _, err := a.Write([]byte("x")); err == nil {
+		t.Error("Write() after Close() succeeded, want error")
+	}
+
+	if f.OsFile() == nil || f.ref == nil {
+		t.Fatal("file closed while a handle is open")
+	}
+	ff := f.File
+	if err := b.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if f.ref != nil {
+		t.Error("file still attached after last handle closed")
+	}
+	if err := ff.Close(); err == nil {
+		t.Error("underlying file still open after last handle closed")
+	}
+}
@@ -0,0 +1,94 @@
+package basicfile
+
+import "strings"
+
+// iniSyntax is the syntax of INI files:
+//
+//	; comment
+//	global = value
+//	[section]
+//	key = value
+//	other: "  quoted value  "
+//
+// Comments start with ';' or '#' and fill their line.
+// Keys are "section.key", or just "key" before the
+// first section.
+type iniSyntax struct{}
+
+// OpenINI opens the named INI file as a ConfigFile. The
+// key of a setting in a section is the section name and
+// the setting name joined by '.', e.g. "server.port";
+// settings before the first section have no section
+// name. Keys and values are trimmed of surrounding
+// spaces and a value in double quotes is unquoted.
+//
+// If there is an error, it will be of type *GoFileError.
+func OpenINI(name string, opts ...TextOption) (ConfigFile, error) {
+	return openConfig(name, iniSyntax{}, opts...)
+}
+
+func (iniSyntax) parse(lines []string) []configEntry {
+	var entries []configEntry
+	section := ""
+	for i, line := range lines {
+		t := strings.TrimSpace(line)
+		switch {
+		case t == "" || t[0] == ';' || t[0] == '#':
+			continue
+		case t[0] == '[' && t[len(t)-1] == ']':
+			section = strings.TrimSpace(t[1 : len(t)-1])
+			continue
+		}
+
+		j := strings.IndexAny(line, "=:")
+		if j < 0 {
+			continue
+		}
+		key := strings.TrimSpace(line[:j])
+		if key == "" {
+			continue
+		}
+		rest := line[j+1:]
+		v := strings.TrimLeft(rest, " \t")
+		entries = append(entries, configEntry{
+			section: section,
+			key:     key,
+			value:   iniUnquote(strings.TrimRight(v, " \t")),
+			first:   i,
+			last:    i,
+			prefix:  line[:len(line)-len(v)],
+		})
+	}
+	return entries
+}
+
+func (iniSyntax) format(prefix, value string) (string, bool) {
+	if strings.ContainsAny(value, "\r\n") {
+		return "", false
+	}
+	if value != strings.TrimSpace(value) || iniUnquote(value) != value {
+		value = `"` + value + `"`
+	}
+	return prefix + value, true
+}
+
+func (iniSyntax) prefix(section, key string) string {
+	if key != strings.TrimSpace(key) || strings.ContainsAny(key, "=:\r\n") ||
+		strings.ContainsAny(section, "]\r\n") {
+		return ""
+	}
+	if c := key[0]; c == ';' || c == '#' || c == '[' {
+		return ""
+	}
+	return key + " = "
+}
+func (iniSyntax) sections() bool               { return true }
+func (iniSyntax) header(section string) string { return "[" + section + "]" }
+
+// iniUnquote removes the double quotes around s, if any.
+func iniUnquote(s string) string {
+	if len(s) >= 2 && s[0] == '"' && s[len(s)-1] == '"' {
+		return s[1 : len(s)-1]
+	}
+	return s
+}
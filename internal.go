@@ -1,90 +1,55 @@
 package basicfile
 
 import (
-	"bufio"
+	"context"
 	"io"
 	"os"
 	"syscall"
 	"time"
 )
 
-// // NewBufferedSectionWriter converts incoming Write() requests into
-// // buffered, asynchronous WriteAt()'s in a section of a file.
-// // Reference: https://github.com/couchbase/moss
-// func NewBufferedSectionWriter(w io.WriterAt, begPos, maxBytes int64,
-// 	bufSize int) *bufferedSectionWriter {
-// 	stopCh := make(chan struct{})
-// 	doneCh := make(chan struct{})
-// 	reqCh := make(chan ioBuf)
-// 	resCh := make(chan ioBuf)
-
-// 	go func() {
-// 		defer close(doneCh)
-// 		defer close(resCh)
-
-// 		buf := make([]byte, bufSize)
-// 		var pos int64
-// 		var err error
-
-// 		for {
-// 			select {
-// 			case <-stopCh:
-// 				return
-// 			case resCh <- ioBuf{buf: buf, pos: pos, err: err}:
-// 			}
-
-// 			req, ok := <-reqCh
-// 			if ok {
-// 				buf, pos = req.buf, req.pos
-// 				if len(buf) > 0 {
-// 					_, err = w.WriteAt(buf, pos)
-// 				}
-// 			}
-// 		}
-// 	}()
-
-// 	return &bufferedSectionWriter{
-// 		w:   w,
-// 		beg: begPos,
-// 		cur: begPos,
-// 		max: maxBytes,
-// 		buf: make([]byte, bufSize),
-
-// 		stopCh: stopCh,
-// 		doneCh: doneCh,
-// 		reqCh:  reqCh,
-// 		resCh:  resCh,
-// 	}
-// }
-
-// replicate is a function found at
+// replicate copies src to dst, starting at byte offset
+// off, until src returns io.EOF. buf is used for each
+// block; progress, if not nil, is called with the
+// number of bytes in each block written.
+//
+// It returns the number of bytes copied. The copy stops
+// early if ctx is canceled or a read or write fails.
+//
+// Based on a function found at
 // Reference: https://github.com/maxymania/metaclusterfs
-func replicate(dst io.WriterAt, src io.ReaderAt) (err error) {
-	buf := make([]byte, 1<<12)
-	p := int64(0)
+func replicate(ctx context.Context, dst io.WriterAt, src io.ReaderAt, off int64, buf []byte, progress func(n int)) (written int64, err error) {
+	p := off
 	for {
+		if err = ctx.Err(); err != nil {
+			return written, err
+		}
+
 		n, e := src.ReadAt(buf, p)
-		err = e
 		if n > 0 {
-			dst.WriteAt(buf[:n], p)
+			w, we := dst.WriteAt(buf[:n], p)
+			written += int64(w)
+			if we == nil && w < n {
+				we = io.ErrShortWrite
+			}
+			if we != nil {
+				return written, we
+			}
+			if progress != nil {
+				progress(n)
+			}
+			p += int64(n)
+		}
+		if e == io.EOF {
+			return written, nil
 		}
-		if err != nil {
-			break
+		if e != nil {
+			return written, e
 		}
 	}
-	return
 }
 
 type (
-
-	// Implements Handle by implementing
-	// io.ReadWriteCloser (with io.StringWriter)
-	// for bufio.ReadWriter
-	bufRWC struct {
-		*bufio.ReadWriter
-		Closer
-	}
-
 	// RWToFrom implements io.ReaderFrom and io.WriterTo
 	RWToFrom interface {
 		io.ReaderFrom
@@ -131,11 +96,6 @@ type (
 	}
 )
 
-func (b *bufRWC) Close() error {
-	// TODO: implement this ...
-	return nil
-}
-
 // SameFile reports whether fi1 and fi2 describe the same file.
 // For example, on Unix this means that the device and inode fields
 // of the two underlying structures are identical; on other systems
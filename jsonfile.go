@@ -0,0 +1,567 @@
+package basicfile
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"strings"
+	"sync"
+)
+
+type (
+	// JSONFile is a BasicFile holding a JSON document in
+	// memory. Values are addressed by paths such as
+	//  a.b[2].c
+	// where names are object keys and [n] are array
+	// indexes. A key that contains '.' or '[' may be
+	// written ["a.b"] or a\.b.
+	//
+	// The order of object keys is preserved, and numbers
+	// that are not changed are written back exactly as
+	// they were read.
+	JSONFile interface {
+		BasicFile
+
+		// Get returns the value at path, as
+		// encoding/json would decode it into an
+		// interface{} with UseNumber: numbers are
+		// json.Number, so large integers are exact.
+		Get(path string) (interface{}, error)
+
+		// Set sets the value at path to value, encoded
+		// as encoding/json would encode it.
+		Set(path string, value interface{}) error
+
+		// Delete removes the value at path.
+		Delete(path string) error
+
+		// Unmarshal decodes the document into v, as
+		// json.Unmarshal does.
+		Unmarshal(v interface{}) error
+
+		// UnmarshalPath decodes the value at path
+		// into v, as json.Unmarshal does.
+		UnmarshalPath(path string, v interface{}) error
+
+		// Bytes returns the document as it would be
+		// saved.
+		Bytes() ([]byte, error)
+
+		// Save writes the document to the file
+		// atomically.
+		Save() error
+
+		// Reload reads the document from the file again,
+		// discarding unsaved changes.
+		Reload() error
+	}
+
+	// A JSONOption configures a JSONFile.
+	JSONOption func(*jsonFile)
+
+	jsonFile struct {
+		basicFile
+		jmu      sync.Mutex // guards the fields below
+		doc      interface{}
+		modified bool
+		style    jsonStyle
+		styleSet bool // style was given, not detected
+	}
+
+	// jsonStyle is the layout used to save a document.
+	jsonStyle struct {
+		indent  string // "" for compact
+		newline bool   // end with a newline
+	}
+
+	// jsonObject is a JSON object that keeps the order of
+	// its keys.
+	jsonObject struct {
+		keys []string
+		vals map[string]interface{}
+	}
+
+	// jsonArray is a JSON array. It is a pointer so that
+	// it may grow in place.
+	jsonArray struct {
+		elems []interface{}
+	}
+)
+
+// WithIndent saves the document pretty-printed with each
+// level indented by indent. By default, the layout of the
+// file when it was loaded is kept.
+func WithIndent(indent string) JSONOption {
+	return func(d *jsonFile) {
+		d.style = jsonStyle{indent: indent, newline: true}
+		d.styleSet = true
+	}
+}
+
+// WithCompact saves the document without insignificant
+// white space.
+func WithCompact() JSONOption {
+	return func(d *jsonFile) {
+		d.style = jsonStyle{}
+		d.styleSet = true
+	}
+}
+
+// OpenJSON opens the named file and reads the JSON
+// document in it.
+//
+// If there is an error, it will be of type *GoFileError.
+func OpenJSON(name string, opts ...JSONOption) (JSONFile, error) {
+	d := &jsonFile{}
+	d.basicFile.init(name, WithReadOnly())
+	for _, opt := range opts {
+		opt(d)
+	}
+	if err := d.Reload(); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+func (d *jsonFile) Reload() error {
+	b, err := os.ReadFile(d.providedName)
+	if err != nil {
+		return Err(NewGoFileError("gofile.OpenJSON", d.providedName, err))
+	}
+	b = bytes.TrimPrefix(b, BOMUTF8.Bytes())
+
+	doc, err := parseJSON(b)
+	if err != nil {
+		return d.jsonError("gofile.OpenJSON", err)
+	}
+
+	d.jmu.Lock()
+	defer d.jmu.Unlock()
+	d.doc = doc
+	d.modified = false
+	if !d.styleSet {
+		d.style = detectJSONStyle(b)
+	}
+	d.basicFile.Dirty()
+	return nil
+}
+
+func (d *jsonFile) Get(path string) (interface{}, error) {
+	d.jmu.Lock()
+	defer d.jmu.Unlock()
+
+	v, err := d.lookup(path)
+	if err != nil {
+		return nil, d.jsonError("gofile.Get", err)
+	}
+	return plainJSON(v), nil
+}
+
+func (d *jsonFile) UnmarshalPath(path string, v interface{}) error {
+	d.jmu.Lock()
+	defer d.jmu.Unlock()
+
+	node, err := d.lookup(path)
+	if err != nil {
+		return d.jsonError("gofile.UnmarshalPath", err)
+	}
+	var buf bytes.Buffer
+	writeJSON(&buf, node)
+	if err := json.Unmarshal(buf.Bytes(), v); err != nil {
+		return d.jsonError("gofile.UnmarshalPath", err)
+	}
+	return nil
+}
+
+func (d *jsonFile) Unmarshal(v interface{}) error {
+	return d.UnmarshalPath("", v)
+}
+
+// Set sets the value at path, replacing any value there.
+// Missing objects on the way to path are created. An
+// array index may be the length of the array, to append
+// to it.
+//
+// Changes are made in memory and written by Save.
+func (d *jsonFile) Set(path string, value interface{}) error {
+	b, err := json.Marshal(value)
+	if err != nil {
+		return d.jsonError("gofile.Set", err)
+	}
+	node, err := parseJSON(b)
+	if err != nil {
+		return d.jsonError("gofile.Set", err)
+	}
+
+	d.jmu.Lock()
+	defer d.jmu.Unlock()
+
+	segs, err := parseJSONPath(path)
+	if err != nil {
+		return d.jsonError("gofile.Set", err)
+	}
+	if len(segs) == 0 {
+		d.doc = node
+		d.modified = true
+		return nil
+	}
+
+	parent, err := d.walk(path, segs[:len(segs)-1], true)
+	if err != nil {
+		return d.jsonError("gofile.Set", err)
+	}
+	last := segs[len(segs)-1]
+	switch p := parent.(type) {
+	case *jsonObject:
+		if !last.isKey {
+			return d.jsonError("gofile.Set", pathErr(path, fs.ErrInvalid))
+		}
+		p.set(last.key, node)
+	case *jsonArray:
+		switch {
+		case last.isKey || last.index > len(p.elems):
+			return d.jsonError("gofile.Set", pathErr(path, fs.ErrInvalid))
+		case last.index == len(p.elems):
+			p.elems = append(p.elems, node)
+		default:
+			p.elems[last.index] = node
+		}
+	default:
+		return d.jsonError("gofile.Set", pathErr(path, fs.ErrInvalid))
+	}
+	d.modified = true
+	return nil
+}
+
+// Delete removes the value at path. Later elements of
+// an array move down by one.
+//
+// Changes are made in memory and written by Save.
+func (d *jsonFile) Delete(path string) error {
+	d.jmu.Lock()
+	defer d.jmu.Unlock()
+
+	segs, err := parseJSONPath(path)
+	if err != nil {
+		return d.jsonError("gofile.Delete", err)
+	}
+	if len(segs) == 0 {
+		return d.jsonError("gofile.Delete", pathErr(path, fs.ErrInvalid))
+	}
+
+	parent, err := d.walk(path, segs[:len(segs)-1], false)
+	if err != nil {
+		return d.jsonError("gofile.Delete", err)
+	}
+	last := segs[len(segs)-1]
+	switch p := parent.(type) {
+	case *jsonObject:
+		if last.isKey && p.delete(last.key) {
+			d.modified = true
+			return nil
+		}
+	case *jsonArray:
+		if !last.isKey && last.index < len(p.elems) {
+			p.elems = append(p.elems[:last.index], p.elems[last.index+1:]...)
+			d.modified = true
+			return nil
+		}
+	}
+	return d.jsonError("gofile.Delete", pathErr(path, fs.ErrNotExist))
+}
+
+func (d *jsonFile) Bytes() ([]byte, error) {
+	d.jmu.Lock()
+	defer d.jmu.Unlock()
+	return d.bytes()
+}
+
+// Save writes the document to the file atomically, as
+// WriteFileAtomic does, keeping the file mode. Save does
+// nothing if the document has not been changed.
+//
+// If there is an error, it will be of type *GoFileError.
+func (d *jsonFile) Save() error {
+	d.jmu.Lock()
+	defer d.jmu.Unlock()
+
+	if !d.modified {
+		return nil
+	}
+	b, err := d.bytes()
+	if err != nil {
+		return d.jsonError("gofile.Save", err)
+	}
+	fi, err := d.basicFile.Stat()
+	if err != nil {
+		return err
+	}
+	if err := WriteFileAtomic(d.providedName, b, fi.Mode().Perm()); err != nil {
+		return err
+	}
+	d.modified = false
+	d.basicFile.Close()
+	d.basicFile.Dirty()
+	return nil
+}
+
+// bytes encodes the document in the saved style. The
+// caller must hold d.jmu.
+func (d *jsonFile) bytes() ([]byte, error) {
+	var buf bytes.Buffer
+	writeJSON(&buf, d.doc)
+	b := buf.Bytes()
+
+	if d.style.indent != "" {
+		var out bytes.Buffer
+		if err := json.Indent(&out, b, "", d.style.indent); err != nil {
+			return nil, err
+		}
+		b = out.Bytes()
+	}
+	if d.style.newline {
+		b = append(b, '\n')
+	}
+	return b, nil
+}
+
+// lookup returns the value at path. The caller must
+// hold d.jmu.
+func (d *jsonFile) lookup(path string) (interface{}, error) {
+	segs, err := parseJSONPath(path)
+	if err != nil {
+		return nil, err
+	}
+	return d.walk(path, segs, false)
+}
+
+// walk returns the value reached by following segs from
+// the root of the document. If create is true, missing
+// object keys are added with empty objects as values,
+// and a path through a value that is not an object or
+// array is invalid rather than missing. The caller must
+// hold d.jmu.
+func (d *jsonFile) walk(path string, segs []pathSegment, create bool) (interface{}, error) {
+	v := d.doc
+	for i, seg := range segs {
+		switch node := v.(type) {
+		case *jsonObject:
+			if !seg.isKey {
+				return nil, pathErr(path, fs.ErrInvalid)
+			}
+			next, ok := node.vals[seg.key]
+			if !ok {
+				if !create {
+					return nil, pathErr(path, fs.ErrNotExist)
+				}
+				next = newJSONObject()
+				node.set(seg.key, next)
+			}
+			v = next
+		case *jsonArray:
+			if seg.isKey {
+				return nil, pathErr(path, fs.ErrInvalid)
+			}
+			if seg.index >= len(node.elems) {
+				return nil, pathErr(path, fs.ErrNotExist)
+			}
+			v = node.elems[seg.index]
+		default:
+			if create && i == 0 && v == nil && seg.isKey {
+				d.doc = newJSONObject()
+				return d.walk(path, segs, create)
+			}
+			if create {
+				return nil, pathErr(path, fs.ErrInvalid)
+			}
+			return nil, pathErr(path, fs.ErrNotExist)
+		}
+	}
+	return v, nil
+}
+
+// jsonError returns err as a *GoFileError.
+func (d *jsonFile) jsonError(op string, err error) error {
+	return Err(&GoFileError{
+		Op:   prependGoFilePrefix(op),
+		Path: d.providedName,
+		Err:  err,
+	})
+}
+
+// pathErr returns err annotated with a JSON path.
+func pathErr(path string, err error) error {
+	return fmt.Errorf("json path %q: %w", path, err)
+}
+
+func newJSONObject() *jsonObject {
+	return &jsonObject{vals: make(map[string]interface{})}
+}
+
+// set sets the value of key, adding key at the end if
+// it is new.
+func (o *jsonObject) set(key string, v interface{}) {
+	if _, ok := o.vals[key]; !ok {
+		o.keys = append(o.keys, key)
+	}
+	o.vals[key] = v
+}
+
+// delete removes key and reports whether it was present.
+func (o *jsonObject) delete(key string) bool {
+	if _, ok := o.vals[key]; !ok {
+		return false
+	}
+	delete(o.vals, key)
+	for i, k := range o.keys {
+		if k == key {
+			o.keys = append(o.keys[:i], o.keys[i+1:]...)
+			break
+		}
+	}
+	return true
+}
+
+// parseJSON parses a single JSON value from b into
+// jsonObject, jsonArray, json.Number, string, bool and
+// nil values.
+func parseJSON(b []byte) (interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+
+	v, err := parseJSONValue(dec)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, fmt.Errorf("invalid data after top-level value at offset %d", dec.InputOffset())
+	}
+	return v, nil
+}
+
+func parseJSONValue(dec *json.Decoder) (interface{}, error) {
+	tok, err := dec.Token()
+	if err == io.EOF {
+		return nil, io.ErrUnexpectedEOF
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	switch tok {
+	case json.Delim('{'):
+		obj := newJSONObject()
+		for dec.More() {
+			key, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			v, err := parseJSONValue(dec)
+			if err != nil {
+				return nil, err
+			}
+			obj.set(key.(string), v)
+		}
+		_, err := dec.Token()
+		return obj, err
+
+	case json.Delim('['):
+		arr := &jsonArray{elems: []interface{}{}}
+		for dec.More() {
+			v, err := parseJSONValue(dec)
+			if err != nil {
+				return nil, err
+			}
+			arr.elems = append(arr.elems, v)
+		}
+		_, err := dec.Token()
+		return arr, err
+	}
+	return tok, nil
+}
+
+// writeJSON writes v, a parsed JSON value, compactly.
+func writeJSON(w *bytes.Buffer, v interface{}) {
+	switch node := v.(type) {
+	case *jsonObject:
+		w.WriteByte('{')
+		for i, k := range node.keys {
+			if i > 0 {
+				w.WriteByte(',')
+			}
+			writeJSONString(w, k)
+			w.WriteByte(':')
+			writeJSON(w, node.vals[k])
+		}
+		w.WriteByte('}')
+	case *jsonArray:
+		w.WriteByte('[')
+		for i, e := range node.elems {
+			if i > 0 {
+				w.WriteByte(',')
+			}
+			writeJSON(w, e)
+		}
+		w.WriteByte(']')
+	case string:
+		writeJSONString(w, node)
+	case json.Number:
+		w.WriteString(node.String())
+	case bool:
+		if node {
+			w.WriteString("true")
+		} else {
+			w.WriteString("false")
+		}
+	default:
+		w.WriteString("null")
+	}
+}
+
+// writeJSONString writes s as a JSON string without
+// escaping HTML characters.
+func writeJSONString(w *bytes.Buffer, s string) {
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	enc.Encode(s)
+	w.Truncate(w.Len() - 1) // Encode adds a newline
+}
+
+// plainJSON converts a parsed JSON value to the types
+// used by encoding/json for interface{} values, keeping
+// numbers as json.Number, as UseNumber does.
+func plainJSON(v interface{}) interface{} {
+	switch node := v.(type) {
+	case *jsonObject:
+		m := make(map[string]interface{}, len(node.keys))
+		for k, e := range node.vals {
+			m[k] = plainJSON(e)
+		}
+		return m
+	case *jsonArray:
+		s := make([]interface{}, len(node.elems))
+		for i, e := range node.elems {
+			s[i] = plainJSON(e)
+		}
+		return s
+	}
+	return v
+}
+
+// detectJSONStyle returns the layout of b, a JSON
+// document: the indent of its first indented line, or
+// compact if it has none.
+func detectJSONStyle(b []byte) jsonStyle {
+	s := jsonStyle{newline: bytes.HasSuffix(b, []byte("\n"))}
+	for _, line := range strings.Split(string(b), "\n")[1:] {
+		trimmed := strings.TrimLeft(line, " \t")
+		if trimmed != "" && len(trimmed) < len(line) {
+			s.indent = line[:len(line)-len(trimmed)]
+			break
+		}
+	}
+	return s
+}
@@ -0,0 +1,162 @@
+package basicfile
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"reflect"
+	"testing"
+)
+
+const stateJSON = `{
+  "name": "svc",
+  "version": 12345678901234567890,
+  "a": {
+    "b": [
+      {"c": 1},
+      {"c": 2},
+      {"c": "three", "d.e": true}
+    ]
+  },
+  "html": "<b>&</b>"
+}
+`
+
+func TestJSONFile(t *testing.T) {
+	path := writeTestFile(t, "state.json", stateJSON)
+
+	f, err := OpenJSON(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		path string
+		want interface{}
+	}{
+		{"name", "svc"},
+		{"version", json.Number("12345678901234567890")},
+		{"a.b[1].c", json.Number("2")},
+		{"a.b[2].c", "three"},
+		{`a.b[2]["d.e"]`, true},
+		{`a.b[2].d\.e`, true},
+		{"a.b[0]", map[string]interface{}{"c": json.Number("1")}},
+	}
+	for _, tt := range tests {
+		got, err := f.Get(tt.path)
+		if err != nil || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Get(%q) = %v, %v, want %v", tt.path, got, err, tt.want)
+		}
+	}
+	for _, p := range []string{"nope", "a.b[9]", "name.x"} {
+		if _, err := f.Get(p); !errors.Is(err, ErrNotExist) {
+			t.Errorf("Get(%q) = %v, want ErrNotExist", p, err)
+		}
+	}
+	for _, p := range []string{"a..b", "a.b[x]", "a.b[0", "a.b.c"} {
+		if _, err := f.Get(p); !errors.Is(err, ErrInvalid) {
+			t.Errorf("Get(%q) = %v, want ErrInvalid", p, err)
+		}
+	}
+
+	var c struct {
+		C string `json:"c"`
+	}
+	if err := f.UnmarshalPath("a.b[2]", &c); err != nil || c.C != "three" {
+		t.Errorf("UnmarshalPath() = %v, %+v", err, c)
+	}
+
+	if err := f.Set("a.b[1].c", 20); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Set("a.b[3]", map[string]int{"c": 4}); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Set("new.nested", []string{"x"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Set("a.b[9]", 1); !errors.Is(err, ErrInvalid) {
+		t.Errorf("Set() past end of array = %v, want ErrInvalid", err)
+	}
+	if err := f.Set("name.x", 1); !errors.Is(err, ErrInvalid) {
+		t.Errorf("Set() through a string = %v, want ErrInvalid", err)
+	}
+	if err := f.Delete("a.b[0]"); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Delete("name"); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Delete("name"); !errors.Is(err, ErrNotExist) {
+		t.Errorf("Delete() twice = %v, want ErrNotExist", err)
+	}
+
+	if err := f.Save(); err != nil {
+		t.Fatal(err)
+	}
+	want := `{
+  "version": 12345678901234567890,
+  "a": {
+    "b": [
+      {
+        "c": 20
+      },
+      {
+        "c": "three",
+        "d.e": true
+      },
+      {
+        "c": 4
+      }
+    ]
+  },
+  "html": "<b>&</b>",
+  "new": {
+    "nested": [
+      "x"
+    ]
+  }
+}
+`
+	if b, _ := os.ReadFile(path); string(b) != want {
+		t.Errorf("saved:\n%s\nwant:\n%s", b, want)
+	}
+
+	var doc struct {
+		Version uint64 `json:"version"`
+	}
+	if err := f.Unmarshal(&doc); err != nil || doc.Version != 12345678901234567890 {
+		t.Errorf("Unmarshal() = %v, %+v", err, doc)
+	}
+}
+
+func TestJSONFileCompact(t *testing.T) {
+	path := writeTestFile(t, "compact.json", `{"b":1,"a":[true,null]}`)
+
+	f, err := OpenJSON(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Set("c", "x"); err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := f.Bytes(); string(b) != `{"b":1,"a":[true,null],"c":"x"}` {
+		t.Errorf("Bytes() = %s", b)
+	}
+
+	f, err = OpenJSON(path, WithIndent("\t"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := f.Bytes(); string(b) != "{\n\t\"b\": 1,\n\t\"a\": [\n\t\ttrue,\n\t\tnull\n\t]\n}\n" {
+		t.Errorf("Bytes() with indent = %q", b)
+	}
+}
+
+func TestOpenJSON_invalid(t *testing.T) {
+	for _, data := range []string{"", "{", `{"a":1} x`, `{"a" 1}`} {
+		if _, err := OpenJSON(writeTestFile(t, "bad.json", data)); err == nil {
+			t.Errorf("OpenJSON(%q) succeeded, want error", data)
+		}
+	}
+}
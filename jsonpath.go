@@ -0,0 +1,108 @@
+package basicfile
+
+import (
+	"fmt"
+	"io/fs"
+	"strconv"
+	"strings"
+)
+
+// pathSegment is an object key or array index in a
+// JSON path.
+type pathSegment struct {
+	key   string
+	index int
+	isKey bool
+}
+
+func (s pathSegment) String() string {
+	if s.isKey {
+		return strconv.Quote(s.key)
+	}
+	return strconv.Itoa(s.index)
+}
+
+// parseJSONPath splits a path such as
+//
+//	a.b[2].c
+//	servers["eu.west"].hosts[0]
+//
+// into object keys and array indexes. In a bare key, a
+// backslash escapes the next character. The empty path
+// refers to the whole document.
+func parseJSONPath(path string) ([]pathSegment, error) {
+	var segs []pathSegment
+	bad := func() ([]pathSegment, error) {
+		return nil, fmt.Errorf("json path %q: %w", path, fs.ErrInvalid)
+	}
+
+	for i := 0; i < len(path); {
+		switch c := path[i]; {
+		case c == '[':
+			end := strings.IndexByte(path[i:], ']')
+			if end < 0 {
+				return bad()
+			}
+			inner := path[i+1 : i+end]
+			if strings.HasPrefix(inner, `"`) {
+				// A quoted key may contain ']', so find
+				// the end of the string first.
+				key, rest, err := unquotePrefix(path[i+1:])
+				if err != nil || !strings.HasPrefix(rest, "]") {
+					return bad()
+				}
+				segs = append(segs, pathSegment{key: key, isKey: true})
+				i = len(path) - len(rest) + 1
+			} else {
+				n, err := strconv.Atoi(inner)
+				if err != nil || n < 0 {
+					return bad()
+				}
+				segs = append(segs, pathSegment{index: n})
+				i += end + 1
+			}
+			if i < len(path) && path[i] == '.' {
+				i++
+				if i == len(path) {
+					return bad()
+				}
+			}
+
+		default:
+			var key strings.Builder
+			for i < len(path) && path[i] != '.' && path[i] != '[' {
+				if path[i] == '\\' && i+1 < len(path) {
+					i++
+				}
+				key.WriteByte(path[i])
+				i++
+			}
+			if key.Len() == 0 {
+				return bad()
+			}
+			segs = append(segs, pathSegment{key: key.String(), isKey: true})
+			if i < len(path) && path[i] == '.' {
+				i++
+				if i == len(path) {
+					return bad()
+				}
+			}
+		}
+	}
+	return segs, nil
+}
+
+// unquotePrefix unquotes the Go or JSON string literal
+// at the start of s and returns the rest of s.
+func unquotePrefix(s string) (string, string, error) {
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '"':
+			v, err := strconv.Unquote(s[:i+1])
+			return v, s[i+1:], err
+		}
+	}
+	return "", "", strconv.ErrSyntax
+}
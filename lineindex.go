@@ -0,0 +1,203 @@
+package basicfile
+
+import (
+	"hash"
+	"hash/crc64"
+	"io"
+	"strings"
+	"time"
+)
+
+// crcTable is the table used to checksum indexed data.
+var crcTable = crc64.MakeTable(crc64.ECMA)
+
+// lineIndex records the byte offset of the start of each
+// line of a file so that lines can be read directly.
+type lineIndex struct {
+	sep       byte
+	enc       Encoding
+	offsets   []int64 // start of each line
+	size      int64   // number of bytes indexed
+	modTime   time.Time
+	lineStart bool        // the byte at size starts a new line
+	start     int64       // offset of the first byte indexed
+	sum       hash.Hash64 // checksum of the bytes indexed
+}
+
+// BuildLineIndex builds or updates the index of line
+// offsets used by ReadLine and ReadLines. The index is
+// kept in memory.
+//
+// If the file has only grown since the index was built,
+// and the previously indexed bytes still have the same
+// checksum, only the new data is indexed. Otherwise,
+// including when the size is unchanged but the
+// modification time is not, the index is rebuilt.
+//
+// It is not usually necessary to call BuildLineIndex;
+// ReadLine and ReadLines update the index as needed.
+func (d *textfile) BuildLineIndex() error {
+	d.tmu.Lock()
+	defer d.tmu.Unlock()
+	return d.indexLocked()
+}
+
+// ReadLine returns line n (1-based) of the file, without
+// its line separator, reading only that line from disk.
+func (d *textfile) ReadLine(n int) (string, error) {
+	lines, err := d.ReadLines(n, n)
+	if err != nil {
+		return "", err
+	}
+	return lines[0], nil
+}
+
+// ReadLines returns lines from through to (1-based and
+// inclusive) of the file, without line separators,
+// reading only those lines from disk.
+func (d *textfile) ReadLines(from, to int) ([]string, error) {
+	d.tmu.Lock()
+	defer d.tmu.Unlock()
+
+	if err := d.indexLocked(); err != nil {
+		return nil, err
+	}
+	return d.readLinesLocked(from, to)
+}
+
+// readLinesLocked is ReadLines, using the line index as
+// it is. The caller must hold d.tmu.
+func (d *textfile) readLinesLocked(from, to int) ([]string, error) {
+	idx := d.index
+	if from < 1 || to < from || to > len(idx.offsets) {
+		return nil, NewGoFileError("gofile.ReadLines", d.providedName, ErrInvalid)
+	}
+
+	start := idx.offsets[from-1]
+	end := idx.lineEnd(to - 1)
+	buf := make([]byte, end-start)
+	if _, err := d.basicFile.ReadAt(buf, start); err != nil && err != io.EOF {
+		return nil, Err(NewGoFileError("gofile.ReadLines", d.providedName, err))
+	}
+
+	lines := make([]string, 0, to-from+1)
+	for i := from - 1; i < to; i++ {
+		s, e := idx.offsets[i]-start, idx.lineEnd(i)-start
+		line, err := decode(buf[s:e], idx.enc, true)
+		if err != nil {
+			err.(*EncodingError).Offset += idx.offsets[i]
+			return nil, Err(&GoFileError{
+				Op:   prependGoFilePrefix("gofile.ReadLines"),
+				Path: d.providedName,
+				Err:  err,
+			})
+		}
+		if idx.sep == '\n' {
+			line = strings.TrimSuffix(line, "\r")
+		}
+		lines = append(lines, line)
+	}
+	return lines, nil
+}
+
+// indexLocked brings the line index up to date with the
+// file on disk. The caller must hold d.tmu.
+func (d *textfile) indexLocked() error {
+	// Force a fresh Stat so that changes made by other
+	// processes are seen.
+	d.basicFile.Dirty()
+	fi, err := d.basicFile.Stat()
+	if err != nil {
+		return err
+	}
+	size, modTime := fi.Size(), fi.ModTime()
+
+	bom, enc := d.encodingAt(&d.basicFile)
+
+	idx := d.index
+	if idx != nil && idx.sep == d.linesep && idx.enc == enc && idx.size == size && idx.modTime.Equal(modTime) {
+		return nil
+	}
+	if idx == nil || idx.sep != d.linesep || idx.enc != enc || size <= idx.size || !d.prefixMatches(idx) {
+		// Skip the byte order mark, as Lines does.
+		start := int64(len(bom.Bytes()))
+		idx = &lineIndex{
+			sep:       d.linesep,
+			enc:       enc,
+			size:      start,
+			start:     start,
+			lineStart: true,
+			sum:       crc64.New(crcTable),
+		}
+	}
+
+	buf := make([]byte, defaultBufSize)
+	for idx.size < size {
+		n, err := d.basicFile.ReadAt(buf[:min64(int64(len(buf)), size-idx.size)], idx.size)
+		if n == 0 && err != nil {
+			d.index = nil
+			return Err(NewGoFileError("gofile.BuildLineIndex", d.providedName, err))
+		}
+		idx.add(buf[:n])
+	}
+	idx.modTime = modTime
+	d.index = idx
+	return nil
+}
+
+// prefixMatches reports whether the bytes indexed by idx
+// are unchanged on disk, by comparing their checksum.
+// The caller must hold d.tmu.
+func (d *textfile) prefixMatches(idx *lineIndex) bool {
+	sum := crc64.New(crcTable)
+	r := io.NewSectionReader(&d.basicFile, idx.start, idx.size-idx.start)
+	if _, err := io.Copy(sum, r); err != nil {
+		return false
+	}
+	return sum.Sum64() == idx.sum.Sum64()
+}
+
+// add indexes b, the bytes following those
+// already indexed, one code unit at a time.
+func (idx *lineIndex) add(b []byte) {
+	unit := idx.enc.unit()
+	for i := 0; i < len(b); i += unit {
+		if idx.lineStart {
+			idx.offsets = append(idx.offsets, idx.size+int64(i))
+			idx.lineStart = false
+		}
+		if i+unit > len(b) {
+			break
+		}
+
+		c := uint16(b[i])
+		if unit == 2 {
+			c = utf16Unit(b[i:], idx.enc)
+		}
+		if c == uint16(idx.sep) {
+			idx.lineStart = true
+		}
+	}
+	idx.size += int64(len(b))
+	idx.sum.Write(b)
+}
+
+// lineEnd returns the offset of the end of line i
+// (0-based), excluding its separator.
+func (idx *lineIndex) lineEnd(i int) int64 {
+	unit := int64(idx.enc.unit())
+	if i+1 < len(idx.offsets) {
+		return idx.offsets[i+1] - unit
+	}
+	if idx.lineStart {
+		return idx.size - unit
+	}
+	return idx.size
+}
+
+func min64(a, b int64) int64 {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,104 @@
+package basicfile
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReadLines(t *testing.T) {
+	var sb strings.Builder
+	for i := 1; i <= 5000; i++ {
+		fmt.Fprintf(&sb, "line %d\r\n", i)
+	}
+	path := writeTestFile(t, "index.txt", sb.String())
+
+	f, err := OpenText(path, WithStreaming())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := f.BuildLineIndex(); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := f.ReadLine(1234)
+	if err != nil || got != "line 1234" {
+		t.Errorf("ReadLine(1234) = %q, %v, want %q", got, err, "line 1234")
+	}
+	lines, err := f.ReadLines(4999, 5000)
+	if want := []string{"line 4999", "line 5000"}; err != nil || !reflect.DeepEqual(lines, want) {
+		t.Errorf("ReadLines(4999, 5000) = %q, %v, want %q", lines, err, want)
+	}
+	for _, r := range [][2]int{{0, 1}, {3, 2}, {5000, 5001}} {
+		if _, err := f.ReadLines(r[0], r[1]); err == nil {
+			t.Errorf("ReadLines(%d, %d) succeeded, want error", r[0], r[1])
+		}
+	}
+
+	// Appended data is indexed, including a final
+	// line without a separator.
+	af, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	af.WriteString("appended\r\ntail")
+	af.Close()
+
+	lines, err = f.ReadLines(5000, 5002)
+	if want := []string{"line 5000", "appended", "tail"}; err != nil || !reflect.DeepEqual(lines, want) {
+		t.Errorf("after append ReadLines() = %q, %v, want %q", lines, err, want)
+	}
+
+	// A rewritten file is indexed from scratch.
+	later := time.Now().Add(time.Minute)
+	if err := os.WriteFile(path, []byte("one\ntwo\nthree"), NormalMode); err != nil {
+		t.Fatal(err)
+	}
+	os.Chtimes(path, later, later)
+
+	lines, err = f.ReadLines(1, 3)
+	if want := []string{"one", "two", "three"}; err != nil || !reflect.DeepEqual(lines, want) {
+		t.Errorf("after rewrite ReadLines() = %q, %v, want %q", lines, err, want)
+	}
+	if _, err := f.ReadLine(4); err == nil {
+		t.Error("ReadLine(4) after rewrite succeeded, want error")
+	}
+}
+
+func TestReadLinesRewritten(t *testing.T) {
+	tail := strings.Repeat("x", 100) + "\n"
+	path := writeTestFile(t, "index.txt", "aa\nbbbb\n"+tail)
+
+	f, err := OpenText(path, WithStreaming())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := f.ReadLine(1); err != nil || got != "aa" {
+		t.Fatalf("ReadLine(1) = %q, %v", got, err)
+	}
+
+	// Same size, same tail, newlines moved.
+	rewrite := func(data string, d time.Duration) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(data), NormalMode); err != nil {
+			t.Fatal(err)
+		}
+		later := time.Now().Add(d)
+		os.Chtimes(path, later, later)
+	}
+	rewrite("aaaa\nbb\n"+tail, time.Minute)
+	lines, err := f.ReadLines(1, 2)
+	if want := []string{"aaaa", "bb"}; err != nil || !reflect.DeepEqual(lines, want) {
+		t.Errorf("after same-size rewrite ReadLines() = %q, %v, want %q", lines, err, want)
+	}
+
+	// Grown, with an edit before the end.
+	rewrite("a\nbbbbb\n"+tail+"new\n", 2*time.Minute)
+	lines, err = f.ReadLines(1, 4)
+	if want := []string{"a", "bbbbb", tail[:len(tail)-1], "new"}; err != nil || !reflect.DeepEqual(lines, want) {
+		t.Errorf("after grown rewrite ReadLines() = %q, %v, want %q", lines, err, want)
+	}
+}
@@ -0,0 +1,357 @@
+package basicfile
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// lockPollInterval is the delay between attempts
+// to acquire a lock in TryLock.
+const lockPollInterval = time.Millisecond
+
+// errWouldBlock is returned by the platform lock
+// functions when a lock is held by another process.
+var errWouldBlock = errors.New("lock held by another process")
+
+type (
+	// FileLocker provides advisory locking of a file.
+	//
+	// Locks are held both within the current process
+	// and, using flock(2), against other processes
+	// that lock the same file. Advisory locks only
+	// exclude other callers that also lock the file.
+	FileLocker interface {
+		// LockShared acquires a shared lock, blocking
+		// until it is available.
+		LockShared() error
+
+		// LockExclusive acquires an exclusive lock,
+		// blocking until it is available.
+		LockExclusive() error
+
+		// TryLock attempts to acquire an exclusive
+		// lock, waiting up to timeout for it.
+		TryLock(timeout time.Duration) error
+
+		// Unlock releases an exclusive lock.
+		Unlock()
+
+		// UnlockShared releases a shared lock.
+		UnlockShared()
+	}
+
+	// fileLock records the inter-process locks held
+	// on the underlying file.
+	fileLock struct {
+		mu        sync.Mutex        // serializes flock state changes
+		shared    int               // number of shared holders
+		exclusive bool              // an exclusive flock is held
+		ranges    map[[2]int64]bool // byte ranges locked, by offset and length
+		held      int32             // locks of any kind held on the fd (atomic)
+	}
+)
+
+// Lock acquires the caller lock of f, blocking until
+// it is available.
+//
+// The caller lock serializes callers that need a
+// sequence of operations on the file to appear
+// atomic to each other. It is independent of the
+// internal locking that protects the file state,
+// so any method of f may be called while it is held.
+//
+// Lock does not exclude other processes; use
+// LockExclusive for that.
+func (f *basicFile) Lock() {
+	f.lk.Lock()
+}
+
+// Unlock releases the exclusive lock acquired by Lock,
+// LockExclusive or TryLock, including any inter-process
+// lock held on the file. As with sync.RWMutex, it is a
+// run-time error if the lock is not held exclusively;
+// a shared lock is released with UnlockShared.
+//
+// Errors releasing the inter-process lock are
+// logged if Err is active.
+func (f *basicFile) Unlock() {
+	f.flk.mu.Lock()
+	if f.flk.exclusive {
+		f.flk.exclusive = false
+		f.releaseFlock()
+	}
+	f.flk.mu.Unlock()
+	f.lk.Unlock()
+}
+
+// UnlockShared releases a shared lock acquired by
+// LockShared. The inter-process lock is released when
+// the last shared holder in this process unlocks. As
+// with sync.RWMutex, it is a run-time error if no
+// shared lock is held.
+//
+// Errors releasing the inter-process lock are
+// logged if Err is active.
+func (f *basicFile) UnlockShared() {
+	f.flk.mu.Lock()
+	if f.flk.shared > 0 {
+		f.flk.shared--
+		if f.flk.shared == 0 {
+			f.releaseFlock()
+		}
+	}
+	f.flk.mu.Unlock()
+	f.lk.RUnlock()
+}
+
+// Locked reports whether the caller lock of f is
+// currently held. The result is advisory; the lock
+// may be acquired or released as soon as Locked
+// returns.
+func (f *basicFile) Locked() bool {
+	if f.lk.TryLock() {
+		f.lk.Unlock()
+		return false
+	}
+	return true
+}
+
+// LockShared acquires a shared lock on f. Any number
+// of callers, in this and other processes, may hold
+// a shared lock at the same time, but not while an
+// exclusive lock is held.
+//
+// It is released with UnlockShared.
+func (f *basicFile) LockShared() error {
+	f.lk.RLock()
+	err := f.acquireFlock("gofile.LockShared", time.Time{}, lockShared, func() bool {
+		if f.flk.shared > 0 {
+			f.flk.shared++
+			return true
+		}
+		return false
+	}, func() {
+		f.flk.shared = 1
+	})
+	if err != nil {
+		f.lk.RUnlock()
+	}
+	return err
+}
+
+// LockExclusive acquires an exclusive lock on f,
+// blocking until no other caller, in this or any
+// other process, holds a lock on the file.
+func (f *basicFile) LockExclusive() error {
+	f.lk.Lock()
+	return f.lockExclusive("gofile.LockExclusive", time.Time{})
+}
+
+// TryLock attempts to acquire an exclusive lock on
+// f, waiting up to timeout for it to become
+// available. If timeout is <= 0, only a single
+// attempt is made.
+//
+// If the lock is held elsewhere, the error returned
+// is a *GoFileError matching ErrFileLocked.
+func (f *basicFile) TryLock(timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for !f.lk.TryLock() {
+		if !time.Now().Before(deadline) {
+			return f.lockedError("gofile.TryLock")
+		}
+		time.Sleep(lockPollInterval)
+	}
+	return f.lockExclusive("gofile.TryLock", deadline)
+}
+
+// lockExclusive acquires the exclusive flock on
+// the file. The caller must hold f.lk exclusively;
+// it is released if an error is returned.
+func (f *basicFile) lockExclusive(op string, deadline time.Time) error {
+	err := f.acquireFlock(op, deadline, lockExclusive, func() bool {
+		return false
+	}, func() {
+		f.flk.exclusive = true
+	})
+	if err != nil {
+		f.lk.Unlock()
+	}
+	return err
+}
+
+// acquireFlock acquires the whole-file flock how,
+// polling until deadline; a zero deadline waits
+// indefinitely. f.flk.mu is held only during each
+// attempt, so that other holders can unlock meanwhile.
+//
+// Before each attempt, have is called with f.flk.mu
+// held; if it reports true, the flock is already held
+// and acquireFlock returns. After the flock is
+// acquired, got is called with f.flk.mu held.
+func (f *basicFile) acquireFlock(op string, deadline time.Time, how int, have func() bool, got func()) error {
+	for {
+		f.flk.mu.Lock()
+		if have() {
+			f.flk.mu.Unlock()
+			return nil
+		}
+		err := f.pollLock(op, time.Now(), func(fd uintptr) error {
+			return flock(fd, how)
+		})
+		if err == nil {
+			got()
+			atomic.AddInt32(&f.flk.held, 1)
+		}
+		f.flk.mu.Unlock()
+
+		switch {
+		case err == nil:
+			return nil
+		case !errors.Is(err, ErrFileLocked):
+			return err
+		case !deadline.IsZero() && !time.Now().Before(deadline):
+			return err
+		}
+		time.Sleep(lockPollInterval)
+	}
+}
+
+// LockRange acquires a byte-range lock covering
+// length bytes starting at off, blocking until it
+// is available. A length of 0 extends the range to
+// the end of the file, however large it grows.
+//
+// Byte-range locks are open file description locks
+// and are independent of the whole-file locks of
+// LockShared and LockExclusive. They do not nest:
+// locking a range that is already locked changes
+// its mode, and a single UnlockRange of the same
+// offset and length releases it.
+func (f *basicFile) LockRange(off, length int64, exclusive bool) error {
+	return f.lockRange("gofile.LockRange", off, length, exclusive, time.Time{})
+}
+
+// TryLockRange is like LockRange but makes a single
+// attempt. If the range is locked elsewhere, the
+// error returned matches ErrFileLocked.
+func (f *basicFile) TryLockRange(off, length int64, exclusive bool) error {
+	return f.lockRange("gofile.TryLockRange", off, length, exclusive, time.Now())
+}
+
+// UnlockRange releases a byte-range lock acquired
+// by LockRange or TryLockRange with the same offset
+// and length. It does nothing if no such lock is
+// held.
+func (f *basicFile) UnlockRange(off, length int64) error {
+	f.flk.mu.Lock()
+	defer f.flk.mu.Unlock()
+
+	key := [2]int64{off, length}
+	if !f.flk.ranges[key] {
+		return nil
+	}
+
+	ff, err := f.rlockFile()
+	if err != nil {
+		return err
+	}
+	defer f.mu.RUnlock()
+
+	err = rangeLock(ff.Fd(), lockRelease, off, length)
+	if err != nil {
+		return Err(NewGoFileError("gofile.UnlockRange", f.providedName, err))
+	}
+	delete(f.flk.ranges, key)
+	atomic.AddInt32(&f.flk.held, -1)
+	return nil
+}
+
+func (f *basicFile) lockRange(op string, off, length int64, exclusive bool, deadline time.Time) error {
+	how := lockShared
+	if exclusive {
+		how = lockExclusive
+	}
+	err := f.pollLock(op, deadline, func(fd uintptr) error {
+		return rangeLock(fd, how, off, length)
+	})
+	if err != nil {
+		return err
+	}
+
+	f.flk.mu.Lock()
+	defer f.flk.mu.Unlock()
+	key := [2]int64{off, length}
+	if !f.flk.ranges[key] {
+		if f.flk.ranges == nil {
+			f.flk.ranges = make(map[[2]int64]bool)
+		}
+		f.flk.ranges[key] = true
+		atomic.AddInt32(&f.flk.held, 1)
+	}
+	return nil
+}
+
+// processLocked reports whether any inter-process
+// lock is held on the underlying file. Closing the
+// file would silently release those locks.
+func (f *basicFile) processLocked() bool {
+	return atomic.LoadInt32(&f.flk.held) > 0
+}
+
+// pollLock calls lock with the descriptor of the
+// underlying file until it succeeds, fails with an
+// error other than errWouldBlock, or deadline has
+// passed. A zero deadline waits indefinitely.
+//
+// The shared lock on f.mu is released between
+// attempts so that a pending Flush or Close is not
+// blocked by another process holding the lock.
+func (f *basicFile) pollLock(op string, deadline time.Time, lock func(fd uintptr) error) error {
+	for {
+		ff, err := f.rlockFile()
+		if err != nil {
+			return err
+		}
+		err = lock(ff.Fd())
+		f.mu.RUnlock()
+
+		switch {
+		case err == nil:
+			return nil
+		case err != errWouldBlock:
+			return Err(NewGoFileError(op, f.providedName, err))
+		case !deadline.IsZero() && !time.Now().Before(deadline):
+			return f.lockedError(op)
+		}
+		time.Sleep(lockPollInterval)
+	}
+}
+
+// releaseFlock releases the whole-file flock. The
+// caller must hold f.flk.mu.
+func (f *basicFile) releaseFlock() {
+	ff, err := f.rlockFile()
+	if err != nil {
+		return
+	}
+	defer f.mu.RUnlock()
+
+	err = flock(ff.Fd(), lockRelease)
+	if err != nil {
+		Err(NewGoFileError("gofile.Unlock", f.providedName, err))
+	}
+	atomic.AddInt32(&f.flk.held, -1)
+}
+
+// lockedError returns a *GoFileError for op that
+// matches ErrFileLocked.
+func (f *basicFile) lockedError(op string) *GoFileError {
+	return &GoFileError{
+		Op:   prependGoFilePrefix(op),
+		Path: f.providedName,
+		Err:  ErrFileLocked,
+	}
+}
@@ -0,0 +1,52 @@
+//go:build linux
+
+package basicfile
+
+import (
+	"io"
+
+	"golang.org/x/sys/unix"
+)
+
+const (
+	lockShared    = unix.LOCK_SH
+	lockExclusive = unix.LOCK_EX
+	lockRelease   = unix.LOCK_UN
+)
+
+// flock applies or removes an advisory lock on the
+// whole file using flock(2). It never blocks; if the
+// lock is held elsewhere, errWouldBlock is returned.
+func flock(fd uintptr, how int) error {
+	err := unix.Flock(int(fd), how|unix.LOCK_NB)
+	if err == unix.EWOULDBLOCK {
+		return errWouldBlock
+	}
+	return err
+}
+
+// rangeLock applies or removes an open file
+// description (OFD) byte-range lock using fcntl(2).
+// It never blocks; if the range is locked elsewhere,
+// errWouldBlock is returned.
+func rangeLock(fd uintptr, how int, off, length int64) error {
+	lk := unix.Flock_t{
+		Whence: io.SeekStart,
+		Start:  off,
+		Len:    length,
+	}
+	switch how {
+	case lockShared:
+		lk.Type = unix.F_RDLCK
+	case lockExclusive:
+		lk.Type = unix.F_WRLCK
+	default:
+		lk.Type = unix.F_UNLCK
+	}
+
+	err := unix.FcntlFlock(fd, unix.F_OFD_SETLK, &lk)
+	if err == unix.EAGAIN || err == unix.EACCES {
+		return errWouldBlock
+	}
+	return err
+}
@@ -0,0 +1,19 @@
+//go:build !linux
+
+package basicfile
+
+const (
+	lockShared    = 1
+	lockExclusive = 2
+	lockRelease   = 8
+)
+
+// flock is not implemented on this platform.
+func flock(fd uintptr, how int) error {
+	return ErrNotImplemented
+}
+
+// rangeLock is not implemented on this platform.
+func rangeLock(fd uintptr, how int, off, length int64) error {
+	return ErrNotImplemented
+}
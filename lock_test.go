@@ -0,0 +1,149 @@
+//go:build linux
+
+package basicfile
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func Test_basicFile_TryLock(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "state.lock")
+	a, err := Create(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// a second BasicFile uses its own descriptor, as
+	// another process would.
+	b, err := NewBasicFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := a.TryLock(0); err != nil {
+		t.Fatalf("TryLock() on unlocked file = %v, want nil", err)
+	}
+	if !a.(*basicFile).Locked() {
+		t.Error("Locked() = false after TryLock(), want true")
+	}
+
+	err = b.TryLock(5 * time.Millisecond)
+	if !errors.Is(err, ErrFileLocked) {
+		t.Errorf("TryLock() on locked file = %v, want ErrFileLocked", err)
+	}
+	var gfe *GoFileError
+	if !errors.As(err, &gfe) || gfe.Path != name {
+		t.Errorf("TryLock() error = %#v, want *GoFileError with path %q", err, name)
+	}
+
+	// Flush syncs but keeps the file, and the lock, open.
+	if err := a.(*basicFile).Flush(); err != nil {
+		t.Errorf("Flush() while locked = %v, want nil", err)
+	}
+	if !a.(*basicFile).Locked() {
+		t.Error("Locked() = false after Flush(), want true")
+	}
+	if err := b.TryLock(0); !errors.Is(err, ErrFileLocked) {
+		t.Errorf("TryLock() after Flush() = %v, want ErrFileLocked", err)
+	}
+
+	a.Unlock()
+	if a.(*basicFile).Locked() {
+		t.Error("Locked() = true after Unlock(), want false")
+	}
+
+	if err := b.LockShared(); err != nil {
+		t.Fatalf("LockShared() after Unlock() = %v, want nil", err)
+	}
+	if err := a.LockShared(); err != nil {
+		t.Fatalf("second LockShared() = %v, want nil", err)
+	}
+	if err := a.TryLock(0); !errors.Is(err, ErrFileLocked) {
+		t.Errorf("TryLock() while shared lock held = %v, want ErrFileLocked", err)
+	}
+	a.UnlockShared()
+	b.UnlockShared()
+}
+
+func Test_basicFile_LockRange(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "ranges.dat")
+	a, err := Create(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := NewBasicFile(name)
+	fa, fb := a.(*basicFile), b.(*basicFile)
+
+	if err := fa.LockRange(0, 100, true); err != nil {
+		t.Fatal(err)
+	}
+	if err := fb.TryLockRange(50, 10, false); !errors.Is(err, ErrFileLocked) {
+		t.Errorf("TryLockRange() overlapping = %v, want ErrFileLocked", err)
+	}
+	if err := fb.TryLockRange(100, 10, true); err != nil {
+		t.Errorf("TryLockRange() disjoint = %v, want nil", err)
+	}
+	if err := fa.UnlockRange(0, 100); err != nil {
+		t.Fatal(err)
+	}
+	if err := fb.UnlockRange(100, 10); err != nil {
+		t.Fatal(err)
+	}
+
+	// Range locks do not nest, and unlocking a range
+	// that is not locked does nothing.
+	for i := 0; i < 2; i++ {
+		if err := fa.LockRange(0, 10, true); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := fa.UnlockRange(0, 10); err != nil {
+		t.Fatal(err)
+	}
+	if err := fa.UnlockRange(20, 10); err != nil {
+		t.Errorf("UnlockRange() of an unlocked range = %v, want nil", err)
+	}
+	if err := fa.Close(); err != nil {
+		t.Errorf("Close() after UnlockRange() = %v, want nil", err)
+	}
+	if err := fa.LockRange(0, 10, true); err != nil {
+		t.Fatal(err)
+	}
+	if err := fa.Close(); !errors.Is(err, ErrFileLocked) {
+		t.Errorf("Close() with a range locked = %v, want ErrFileLocked", err)
+	}
+	fa.UnlockRange(0, 10)
+}
+
+func Test_basicFile_LockSharedWaiting(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "waiting.dat")
+	a, err := Create(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := NewBasicFile(name)
+	fa, fb := a.(*basicFile), b.(*basicFile)
+
+	if err := fb.LockExclusive(); err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan error)
+	go func() { done <- fa.LockShared() }()
+	time.Sleep(10 * lockPollInterval)
+
+	// fa's lock state is not blocked by the waiting LockShared.
+	if err := fa.LockRange(0, 10, false); err != nil {
+		t.Fatal(err)
+	}
+	if err := fa.UnlockRange(0, 10); err != nil {
+		t.Fatal(err)
+	}
+
+	fb.Unlock()
+	if err := <-done; err != nil {
+		t.Fatalf("LockShared() = %v", err)
+	}
+	fa.UnlockShared()
+}
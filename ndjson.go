@@ -0,0 +1,366 @@
+package basicfile
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+type (
+	// NDJSONFile is a TextFile of newline-delimited JSON
+	// (JSON Lines) records, one JSON value per line.
+	// Records are streamed from disk; blank lines are
+	// skipped but still counted in line numbers.
+	//
+	// Record and DecodeRecord find a record by its line
+	// number; RecordAt and DecodeRecordAt find it by its
+	// position among the records, not counting blank
+	// lines.
+	//
+	// Use Each or EachValid to decode records as a type.
+	NDJSONFile interface {
+		TextFile
+
+		// ScanRecords calls fn for each record.
+		ScanRecords(skipMalformed bool, fn func(lineNo int, rec []byte) error) error
+
+		// Record returns the record on line n (1-based).
+		Record(n int) ([]byte, error)
+
+		// DecodeRecord decodes the record on line n
+		// (1-based) into v.
+		DecodeRecord(n int, v interface{}) error
+
+		// RecordAt returns record i (1-based), not
+		// counting blank lines, and its line number.
+		RecordAt(i int) (rec []byte, lineNo int, err error)
+
+		// DecodeRecordAt decodes record i (1-based),
+		// not counting blank lines, into v.
+		DecodeRecordAt(i int, v interface{}) error
+
+		// NumRecords returns the number of records, not
+		// counting blank lines.
+		NumRecords() (int, error)
+
+		// Append adds records to the end of the file
+		// and syncs it.
+		Append(records ...interface{}) error
+	}
+
+	// LineError records a malformed line of a file.
+	LineError struct {
+		Line   int   // 1-based line number
+		Offset int64 // byte offset of the start of the line
+		Err    error
+	}
+
+	// MalformedLines lists the malformed lines skipped
+	// while reading a file.
+	MalformedLines []*LineError
+
+	ndjsonFile struct {
+		textfile
+		recIndex *lineIndex // the line index recLines was built from
+		recLines []int      // line numbers of the records on complete lines
+		scanned  int        // complete lines of recIndex checked for records
+	}
+)
+
+func (e *LineError) Error() string {
+	return fmt.Sprintf("line %d (offset %d): %v", e.Line, e.Offset, e.Err)
+}
+
+func (e *LineError) Unwrap() error {
+	return e.Err
+}
+
+func (m MalformedLines) Error() string {
+	switch len(m) {
+	case 0:
+		return "no malformed lines"
+	case 1:
+		return "1 malformed line: " + m[0].Error()
+	}
+	return fmt.Sprintf("%d malformed lines, first: %v", len(m), m[0])
+}
+
+// OpenNDJSON opens the named file as an NDJSONFile. The
+// file is always streamed; WithStreaming need not be
+// given.
+//
+// If there is an error, it will be of type *GoFileError.
+func OpenNDJSON(name string, opts ...TextOption) (NDJSONFile, error) {
+	d := &ndjsonFile{}
+	d.textfile.init(name, append(opts, WithStreaming())...)
+	if err := d.textfile.open(); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// Each calls fn for each record of f, decoded into a T
+// as json.Unmarshal does, in order.
+//
+// A line that is not valid JSON or cannot be decoded
+// into a T stops the scan with a *GoFileError wrapping
+// a *LineError. An error returned by fn also stops the
+// scan and is returned.
+func Each[T any](f NDJSONFile, fn func(lineNo int, rec T) error) error {
+	return f.ScanRecords(false, decodeEach(fn))
+}
+
+// EachValid is Each, but malformed lines are skipped.
+// When every record has been read, the malformed lines,
+// if any, are returned as a *GoFileError wrapping
+// MalformedLines.
+func EachValid[T any](f NDJSONFile, fn func(lineNo int, rec T) error) error {
+	return f.ScanRecords(true, decodeEach(fn))
+}
+
+// decodeEach returns a ScanRecords function that decodes
+// each record into a T and calls fn.
+func decodeEach[T any](fn func(lineNo int, rec T) error) func(int, []byte) error {
+	return func(lineNo int, b []byte) error {
+		var rec T
+		if err := json.Unmarshal(b, &rec); err != nil {
+			return &LineError{Line: lineNo, Err: err}
+		}
+		return fn(lineNo, rec)
+	}
+}
+
+// ScanRecords calls fn with the line number and raw JSON
+// of each record, in order. The slice is only valid
+// during the call to fn.
+//
+// A line is malformed if it is not valid JSON, or if fn
+// returns a *LineError for it. If skipMalformed is false,
+// a malformed line stops the scan with a *GoFileError
+// wrapping a *LineError. Otherwise it is skipped, and the
+// malformed lines are returned at the end as a
+// *GoFileError wrapping MalformedLines. Any other error
+// returned by fn stops the scan and is returned.
+func (d *ndjsonFile) ScanRecords(skipMalformed bool, fn func(lineNo int, rec []byte) error) error {
+	var bad MalformedLines
+
+	it := d.LineIter()
+	defer it.Close()
+
+	for it.Next() {
+		rec := bytes.TrimSpace(it.Line())
+		if len(rec) == 0 {
+			continue
+		}
+
+		var err error
+		if !json.Valid(rec) {
+			err = &LineError{Line: it.LineNo(), Err: errors.New("invalid JSON")}
+		} else {
+			err = fn(it.LineNo(), rec)
+		}
+		if err == nil {
+			continue
+		}
+
+		var le *LineError
+		if !errors.As(err, &le) {
+			return err
+		}
+		le.Offset = it.Offset()
+		if !skipMalformed {
+			return d.recordError("gofile.ScanRecords", le)
+		}
+		bad = append(bad, le)
+	}
+	if err := it.Err(); err != nil {
+		return err
+	}
+	if len(bad) > 0 {
+		return d.recordError("gofile.ScanRecords", bad)
+	}
+	return nil
+}
+
+// Record returns the record on line n (1-based), read
+// using the line index as ReadLine does. A blank line
+// has no record; the error matches ErrNotExist.
+func (d *ndjsonFile) Record(n int) ([]byte, error) {
+	d.tmu.Lock()
+	defer d.tmu.Unlock()
+
+	if err := d.indexLocked(); err != nil {
+		return nil, err
+	}
+	return d.recordLocked("gofile.Record", n)
+}
+
+// RecordAt returns record i (1-based), counting only the
+// lines that are not blank, and the number of the line
+// it is on. If there is no record i, the error matches
+// ErrNotExist.
+//
+// The line numbers of the records are kept with the line
+// index, so only lines added since the last call are
+// read to find them.
+func (d *ndjsonFile) RecordAt(i int) ([]byte, int, error) {
+	const op = "gofile.RecordAt"
+	d.tmu.Lock()
+	defer d.tmu.Unlock()
+
+	lines, err := d.recordLinesLocked()
+	if err != nil {
+		return nil, 0, err
+	}
+	switch {
+	case i < 1:
+		return nil, 0, d.recordError(op, fmt.Errorf("record %d: %w", i, ErrInvalid))
+	case i > len(lines):
+		return nil, 0, d.recordError(op, fmt.Errorf("record %d: %w", i, ErrNotExist))
+	}
+	b, err := d.recordLocked(op, lines[i-1])
+	if err != nil {
+		return nil, 0, err
+	}
+	return b, lines[i-1], nil
+}
+
+// NumRecords returns the number of lines of the file
+// that are not blank.
+func (d *ndjsonFile) NumRecords() (int, error) {
+	d.tmu.Lock()
+	defer d.tmu.Unlock()
+
+	lines, err := d.recordLinesLocked()
+	if err != nil {
+		return 0, err
+	}
+	return len(lines), nil
+}
+
+// recordLocked returns the record on line n, using the
+// line index as it is. The caller must hold d.tmu.
+func (d *ndjsonFile) recordLocked(op string, n int) ([]byte, error) {
+	lines, err := d.readLinesLocked(n, n)
+	if err != nil {
+		return nil, err
+	}
+	line := strings.TrimSpace(lines[0])
+	if line == "" {
+		return nil, d.recordError(op, &LineError{Line: n, Err: ErrNotExist})
+	}
+	if !json.Valid([]byte(line)) {
+		return nil, d.recordError(op, &LineError{Line: n, Err: errors.New("invalid JSON")})
+	}
+	return []byte(line), nil
+}
+
+// recordLinesLocked brings the line index up to date and
+// returns the line numbers of the lines that are not
+// blank. The caller must hold d.tmu.
+func (d *ndjsonFile) recordLinesLocked() ([]int, error) {
+	const batch = 1024
+	if err := d.indexLocked(); err != nil {
+		return nil, err
+	}
+
+	idx := d.index
+	if idx != d.recIndex {
+		// The index was rebuilt, so the file changed.
+		d.recIndex, d.recLines, d.scanned = idx, nil, 0
+	}
+
+	// A last line without a separator may still grow,
+	// so it is checked again on every call.
+	complete := len(idx.offsets)
+	if !idx.lineStart && complete > 0 {
+		complete--
+	}
+	for d.scanned < complete {
+		to := d.scanned + batch
+		if to > complete {
+			to = complete
+		}
+		lines, err := d.readLinesLocked(d.scanned+1, to)
+		if err != nil {
+			return nil, err
+		}
+		for j, line := range lines {
+			if strings.TrimSpace(line) != "" {
+				d.recLines = append(d.recLines, d.scanned+1+j)
+			}
+		}
+		d.scanned = to
+	}
+
+	recLines := d.recLines
+	if last := len(idx.offsets); complete < last {
+		lines, err := d.readLinesLocked(last, last)
+		if err != nil {
+			return nil, err
+		}
+		if strings.TrimSpace(lines[0]) != "" {
+			recLines = append(recLines[:len(recLines):len(recLines)], last)
+		}
+	}
+	return recLines, nil
+}
+
+// DecodeRecord decodes the record on line n (1-based)
+// into v, as json.Unmarshal does.
+func (d *ndjsonFile) DecodeRecord(n int, v interface{}) error {
+	b, err := d.Record(n)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(b, v); err != nil {
+		return d.recordError("gofile.DecodeRecord", &LineError{Line: n, Err: err})
+	}
+	return nil
+}
+
+// DecodeRecordAt decodes record i (1-based), counting
+// only the lines that are not blank, into v, as
+// json.Unmarshal does.
+func (d *ndjsonFile) DecodeRecordAt(i int, v interface{}) error {
+	b, n, err := d.RecordAt(i)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(b, v); err != nil {
+		return d.recordError("gofile.DecodeRecordAt", &LineError{Line: n, Err: err})
+	}
+	return nil
+}
+
+// Append encodes each record as json.Marshal does and
+// adds them, one per line, to the end of the file. The
+// file is synced before Append returns.
+//
+// If there is an error, it will be of type *GoFileError.
+func (d *ndjsonFile) Append(records ...interface{}) error {
+	d.tmu.Lock()
+	defer d.tmu.Unlock()
+
+	var sb strings.Builder
+	for _, rec := range records {
+		b, err := json.Marshal(rec)
+		if err != nil {
+			return Err(NewGoFileError("gofile.Append", d.providedName, err))
+		}
+		sb.Write(b)
+		sb.WriteString(d.eol)
+	}
+	return d.appendText("gofile.Append", sb.String(), true)
+}
+
+// recordError returns err as a *GoFileError.
+func (d *ndjsonFile) recordError(op string, err error) error {
+	return Err(&GoFileError{
+		Op:   prependGoFilePrefix(op),
+		Path: d.providedName,
+		Err:  err,
+	})
+}
@@ -0,0 +1,170 @@
+package basicfile
+
+import (
+	"errors"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type testEvent struct {
+	ID   int    `json:"id"`
+	Kind string `json:"kind"`
+}
+
+const eventsNDJSON = `{"id":1,"kind":"start"}
+
+{"id":2,"kind":"tick"}
+{"id":
+{"id":"four","kind":"tick"}
+{"id":5,"kind":"stop"}
+`
+
+func TestEach(t *testing.T) {
+	f, err := OpenNDJSON(writeTestFile(t, "events.ndjson", eventsNDJSON))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var ids []int
+	err = Each(f, func(lineNo int, ev testEvent) error {
+		ids = append(ids, ev.ID)
+		return nil
+	})
+	var le *LineError
+	if !errors.As(err, &le) || le.Line != 4 || le.Offset != 48 {
+		t.Fatalf("Each() = %v, want *LineError on line 4 at offset 48", err)
+	}
+	if want := []int{1, 2}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("Each() ids = %v, want %v", ids, want)
+	}
+
+	ids, lines := nil, []int(nil)
+	err = EachValid(f, func(lineNo int, ev testEvent) error {
+		ids = append(ids, ev.ID)
+		lines = append(lines, lineNo)
+		return nil
+	})
+	var bad MalformedLines
+	if _, ok := err.(*GoFileError); !ok || !errors.As(err, &bad) {
+		t.Fatalf("EachValid() = %v, want *GoFileError wrapping MalformedLines", err)
+	}
+	if len(bad) != 2 || bad[0].Line != 4 || bad[1].Line != 5 {
+		t.Errorf("EachValid() malformed = %v, want lines 4 and 5", bad)
+	}
+	if want := []int{1, 2, 5}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("EachValid() ids = %v, want %v", ids, want)
+	}
+	if want := []int{1, 3, 6}; !reflect.DeepEqual(lines, want) {
+		t.Errorf("EachValid() lines = %v, want %v", lines, want)
+	}
+
+	stop := errors.New("stop")
+	if err := EachValid(f, func(int, testEvent) error { return stop }); err != stop {
+		t.Errorf("EachValid() = %v, want error from fn", err)
+	}
+}
+
+func TestNDJSONFileRecordAppend(t *testing.T) {
+	path := writeTestFile(t, "events.ndjson", `{"id":1,"kind":"start"}`)
+
+	f, err := OpenNDJSON(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Append(testEvent{2, "tick"}, testEvent{3, "stop"}); err != nil {
+		t.Fatal(err)
+	}
+
+	want := "{\"id\":1,\"kind\":\"start\"}\n{\"id\":2,\"kind\":\"tick\"}\n{\"id\":3,\"kind\":\"stop\"}\n"
+	if b, _ := os.ReadFile(path); string(b) != want {
+		t.Errorf("after Append file = %q, want %q", b, want)
+	}
+
+	var ev testEvent
+	if err := f.DecodeRecord(3, &ev); err != nil || ev != (testEvent{3, "stop"}) {
+		t.Errorf("DecodeRecord(3) = %v, %+v", err, ev)
+	}
+	if err := f.Append(testEvent{4, "again"}); err != nil {
+		t.Fatal(err)
+	}
+	if b, err := f.Record(4); err != nil || string(b) != `{"id":4,"kind":"again"}` {
+		t.Errorf("Record(4) = %s, %v", b, err)
+	}
+	if _, err := f.Record(5); err == nil {
+		t.Error("Record(5) succeeded, want error")
+	}
+}
+
+func TestNDJSONFileRecordAt(t *testing.T) {
+	path := writeTestFile(t, "events.ndjson", "\n"+`{"id":1,"kind":"start"}`+"\n  \n"+`{"id":2,"kind":"tick"}`)
+
+	f, err := OpenNDJSON(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, err := f.NumRecords(); err != nil || n != 2 {
+		t.Errorf("NumRecords() = %d, %v, want 2", n, err)
+	}
+	if b, n, err := f.RecordAt(2); err != nil || n != 4 || string(b) != `{"id":2,"kind":"tick"}` {
+		t.Errorf("RecordAt(2) = %s, %d, %v", b, n, err)
+	}
+	if _, err := f.Record(3); !errors.Is(err, ErrNotExist) {
+		t.Errorf("Record(3) of a blank line = %v, want ErrNotExist", err)
+	}
+
+	if err := f.Append(testEvent{3, "stop"}); err != nil {
+		t.Fatal(err)
+	}
+	var ev testEvent
+	if err := f.DecodeRecordAt(3, &ev); err != nil || ev != (testEvent{3, "stop"}) {
+		t.Errorf("DecodeRecordAt(3) = %v, %+v", err, ev)
+	}
+	if _, _, err := f.RecordAt(4); !errors.Is(err, ErrNotExist) {
+		t.Errorf("RecordAt(4) = %v, want ErrNotExist", err)
+	}
+	if _, _, err := f.RecordAt(0); !errors.Is(err, ErrInvalid) {
+		t.Errorf("RecordAt(0) = %v, want ErrInvalid", err)
+	}
+
+	// A rewritten file is indexed again.
+	if err := os.WriteFile(path, []byte(`{"id":9,"kind":"new"}`+"\n"), NormalMode); err != nil {
+		t.Fatal(err)
+	}
+	if b, n, err := f.RecordAt(1); err != nil || n != 1 || string(b) != `{"id":9,"kind":"new"}` {
+		t.Errorf("RecordAt(1) after rewrite = %s, %d, %v", b, n, err)
+	}
+	if n, _ := f.NumRecords(); n != 1 {
+		t.Errorf("NumRecords() after rewrite = %d, want 1", n)
+	}
+}
+
+func TestNDJSONFileRecordAtSameSizeRewrite(t *testing.T) {
+	long := `{"id":9,"kind":"` + strings.Repeat("x", 80) + `"}` + "\n"
+	path := writeTestFile(t, "events.ndjson", `{"id":1}`+"\n\n"+`{"id":2}`+"\n"+long)
+
+	f, err := OpenNDJSON(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b, n, err := f.RecordAt(2); err != nil || n != 3 {
+		t.Fatalf("RecordAt(2) = %s, %d, %v", b, n, err)
+	}
+
+	// Same size and tail: the blank line moves.
+	if err := os.WriteFile(path, []byte(`{"id":1}`+"\n"+`{"id":3}`+"\n\n"+long), NormalMode); err != nil {
+		t.Fatal(err)
+	}
+	later := time.Now().Add(time.Minute)
+	os.Chtimes(path, later, later)
+
+	var ev testEvent
+	if err := f.DecodeRecordAt(2, &ev); err != nil || ev.ID != 3 {
+		t.Errorf("DecodeRecordAt(2) after rewrite = %v, %+v", err, ev)
+	}
+	if n, _ := f.NumRecords(); n != 3 {
+		t.Errorf("NumRecords() after rewrite = %d, want 3", n)
+	}
+}
@@ -0,0 +1,163 @@
+package basicfile
+
+import (
+	"bufio"
+	"io"
+	"os"
+)
+
+// accessModes are the mutually exclusive access
+// mode bits of the open flags.
+const accessModes = os.O_RDONLY | os.O_WRONLY | os.O_RDWR
+
+type (
+	// An Option configures how OpenFile opens a file.
+	Option func(*fileConfig)
+
+	// fileConfig records the options used to open a
+	// file so that it can be reopened the same way.
+	fileConfig struct {
+		flag     int         // flags passed to os.OpenFile
+		perm     os.FileMode // mode used if the file is created
+		bufSize  int         // size of bufio buffers; 0 for the default
+		readOnly bool
+		noFollow bool
+		directIO bool
+		lazy     bool // defer opening until the file is used
+	}
+)
+
+// WithFlags sets the flags used to open the file
+// (O_RDWR, O_CREATE, O_APPEND etc.). The default is
+// O_RDWR.
+func WithFlags(flag int) Option {
+	return func(c *fileConfig) { c.flag = flag }
+}
+
+// WithPerm sets the mode (before umask) used if
+// the file is created. The default is NormalMode.
+func WithPerm(perm os.FileMode) Option {
+	return func(c *fileConfig) { c.perm = perm }
+}
+
+// WithBufferSize sets the size of the buffers used
+// by the buffered readers and writers of the file.
+func WithBufferSize(size int) Option {
+	return func(c *fileConfig) { c.bufSize = size }
+}
+
+// WithNoFollow refuses to open a symbolic link in
+// the final element of the path (O_NOFOLLOW), where
+// the platform supports it.
+func WithNoFollow() Option {
+	return func(c *fileConfig) { c.noFollow = true }
+}
+
+// WithDirectIO bypasses the operating system page
+// cache (O_DIRECT), where the platform supports it.
+// Reads and writes must then use buffers, offsets
+// and lengths aligned to the device block size.
+func WithDirectIO() Option {
+	return func(c *fileConfig) { c.directIO = true }
+}
+
+// WithReadOnly opens the file for reading only,
+// regardless of WithFlags. Flags that would create
+// or modify the file are dropped.
+func WithReadOnly() Option {
+	return func(c *fileConfig) { c.readOnly = true }
+}
+
+// withLazy defers opening the file until it is
+// first used.
+func withLazy() Option {
+	return func(c *fileConfig) { c.lazy = true }
+}
+
+// OpenFile is the generalized open call; most users
+// will use Open or Create instead. It opens the named
+// file as configured by opts. By default, the file is
+// opened O_RDWR and must already exist.
+//
+// If the file is later closed by Flush or Close, it is
+// reopened with the same options when next used,
+// except that it is never truncated or exclusively
+// created again.
+//
+// If there is an error, it will be of type *GoFileError.
+func OpenFile(name string, opts ...Option) (BasicFile, error) {
+	f, err := openFile(name, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// openFile returns a new *basicFile configured by
+// opts, opening the file unless withLazy is given.
+func openFile(name string, opts ...Option) (*basicFile, error) {
+	f := &basicFile{}
+	f.init(name, opts...)
+
+	if f.cfg.lazy {
+		return f, nil
+	}
+
+	ff, err := os.OpenFile(name, f.cfg.openFlag(), f.cfg.perm)
+	if err != nil {
+		return nil, Err(NewGoFileError("gofile.OpenFile", name, err))
+	}
+	f.attachLocked(ff, true)
+	f.timeStamp()
+	return f, nil
+}
+
+// init sets the name and configuration of f. It must
+// be called before f is used.
+func (f *basicFile) init(name string, opts ...Option) {
+	f.providedName = name
+	f.cfg = fileConfig{flag: os.O_RDWR, perm: NormalMode}
+	for _, opt := range opts {
+		opt(&f.cfg)
+	}
+}
+
+// openFlag returns the flags used to open the file.
+func (c fileConfig) openFlag() int {
+	flag := c.flag
+	if c.readOnly {
+		flag &^= accessModes | os.O_CREATE | os.O_EXCL | os.O_TRUNC | os.O_APPEND
+		flag |= os.O_RDONLY
+	}
+	if c.noFollow {
+		flag |= oNoFollow
+	}
+	if c.directIO {
+		flag |= oDirect
+	}
+	return flag
+}
+
+// reopenFlag returns the flags used to reopen the
+// file after it has been closed.
+func (c fileConfig) reopenFlag() int {
+	return c.openFlag() &^ (os.O_TRUNC | os.O_EXCL)
+}
+
+// newReader returns a buffered reader of the
+// configured size.
+func (c fileConfig) newReader(r io.Reader) *bufio.Reader {
+	if c.bufSize > 0 {
+		return bufio.NewReaderSize(r, c.bufSize)
+	}
+	return bufio.NewReader(r)
+}
+
+// newWriter returns a buffered writer of the
+// configured size.
+func (c fileConfig) newWriter(w io.Writer) *bufio.Writer {
+	if c.bufSize > 0 {
+		return bufio.NewWriterSize(w, c.bufSize)
+	}
+	return bufio.NewWriter(w)
+}
@@ -0,0 +1,195 @@
+package basicfile
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// propertiesSyntax is the syntax of Java .properties
+// files:
+//
+//	# comment
+//	! comment
+//	key = value
+//	key: value
+//	key value
+//	long = first part \
+//	       second part
+type propertiesSyntax struct{}
+
+// OpenProperties opens the named Java .properties file
+// as a ConfigFile. Keys are separated from values by
+// '=', ':' or white space. A line ending in a backslash
+// continues on the next line, and the escapes \t, \n,
+// \r, \f and \uXXXX are recognized in keys and values.
+//
+// Set replaces a setting that spans several lines with
+// a single line, and writes characters outside ASCII
+// as \uXXXX escapes so that the file can be read as
+// ISO-8859-1 or UTF-8.
+//
+// If there is an error, it will be of type *GoFileError.
+func OpenProperties(name string, opts ...TextOption) (ConfigFile, error) {
+	return openConfig(name, propertiesSyntax{}, opts...)
+}
+
+func (propertiesSyntax) parse(lines []string) []configEntry {
+	var entries []configEntry
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		s := strings.TrimLeft(line, " \t\f")
+		if s == "" || s[0] == '#' || s[0] == '!' {
+			continue
+		}
+
+		// Join continuation lines.
+		first := i
+		logical := s
+		for continues(logical) && i+1 < len(lines) {
+			i++
+			logical = logical[:len(logical)-1] + strings.TrimLeft(lines[i], " \t\f")
+		}
+		if continues(logical) {
+			logical = logical[:len(logical)-1]
+		}
+
+		// The key ends at an unescaped separator.
+		k := 0
+		for k < len(logical) && strings.IndexByte("=: \t\f", logical[k]) < 0 {
+			if logical[k] == '\\' {
+				k++
+			}
+			k++
+		}
+		if k > len(logical) {
+			k = len(logical)
+		}
+		v := strings.TrimLeft(logical[k:], " \t\f")
+		if v != "" && (v[0] == '=' || v[0] == ':') {
+			v = strings.TrimLeft(v[1:], " \t\f")
+		}
+
+		entries = append(entries, configEntry{
+			key:    propertiesUnescape(logical[:k]),
+			value:  propertiesUnescape(v),
+			first:  first,
+			last:   i,
+			prefix: line[:len(line)-len(s)] + logical[:len(logical)-len(v)],
+		})
+	}
+	return entries
+}
+
+// continues reports whether s ends in an odd number of
+// backslashes, continuing on the next line.
+func continues(s string) bool {
+	n := 0
+	for n < len(s) && s[len(s)-1-n] == '\\' {
+		n++
+	}
+	return n%2 == 1
+}
+
+// propertiesUnescape replaces the escapes in s.
+func propertiesUnescape(s string) string {
+	if strings.IndexByte(s, '\\') < 0 {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c != '\\' || i+1 == len(s) {
+			b.WriteByte(c)
+			continue
+		}
+		i++
+		switch s[i] {
+		case 't':
+			b.WriteByte('\t')
+		case 'n':
+			b.WriteByte('\n')
+		case 'r':
+			b.WriteByte('\r')
+		case 'f':
+			b.WriteByte('\f')
+		case 'u':
+			r, ok := unicodeEscape(s[i+1:])
+			if !ok {
+				b.WriteByte('u')
+				continue
+			}
+			i += 4
+			if utf16.IsSurrogate(r) && strings.HasPrefix(s[i+1:], `\u`) {
+				if r2, ok := unicodeEscape(s[i+3:]); ok {
+					if d := utf16.DecodeRune(r, r2); d != utf8.RuneError {
+						r = d
+						i += 6
+					}
+				}
+			}
+			b.WriteRune(r)
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return b.String()
+}
+
+// unicodeEscape returns the rune of the 4 hex digits
+// at the start of s.
+func unicodeEscape(s string) (rune, bool) {
+	if len(s) < 4 {
+		return 0, false
+	}
+	u, err := strconv.ParseUint(s[:4], 16, 16)
+	return rune(u), err == nil
+}
+
+// propertiesEscape escapes s for a .properties file. If
+// key is true, separators and comment characters are
+// also escaped.
+func propertiesEscape(s string, key bool) string {
+	var b strings.Builder
+	for i, r := range s {
+		switch {
+		case r == '\\':
+			b.WriteString(`\\`)
+		case r == '\t':
+			b.WriteString(`\t`)
+		case r == '\n':
+			b.WriteString(`\n`)
+		case r == '\r':
+			b.WriteString(`\r`)
+		case r == '\f':
+			b.WriteString(`\f`)
+		case r == ' ' && (key || i == 0):
+			b.WriteString(`\ `)
+		case key && strings.ContainsRune("=:#!", r):
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r < 0x20 || r > 0x7E:
+			if r1, r2 := utf16.EncodeRune(r); r1 != utf8.RuneError {
+				fmt.Fprintf(&b, `\u%04X\u%04X`, r1, r2)
+			} else {
+				fmt.Fprintf(&b, `\u%04X`, r)
+			}
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+func (propertiesSyntax) format(prefix, value string) (string, bool) {
+	return prefix + propertiesEscape(value, false), true
+}
+
+func (propertiesSyntax) prefix(section, key string) string {
+	return propertiesEscape(key, true) + "="
+}
+
+func (propertiesSyntax) sections() bool               { return false }
+func (propertiesSyntax) header(section string) string { return "" }
@@ -0,0 +1,311 @@
+package basicfile
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"sync"
+)
+
+type (
+	// RecordFile is a file of fixed-size binary records of
+	// type T, encoded with encoding/binary, optionally after
+	// a header. Records are read and written directly at
+	// their offsets with ReadAt and WriteAt.
+	//
+	// T must have a fixed size as reported by
+	// binary.Size: a fixed-size number, or an array or
+	// struct of them.
+	//
+	// Records are indexed from 0.
+	RecordFile[T any] interface {
+		BasicFile
+
+		// Get returns record i.
+		Get(i int) (T, error)
+
+		// GetRange reads records into dst, starting at
+		// record i, and returns the number read. If
+		// fewer than len(dst) records remain, the error
+		// is io.EOF.
+		GetRange(i int, dst []T) (int, error)
+
+		// Put replaces record i, or appends it if i is
+		// Len().
+		Put(i int, v T) error
+
+		// Append adds records to the end of the file.
+		Append(v ...T) error
+
+		// Len returns the number of whole records.
+		Len() (int, error)
+
+		// RecordSize returns the size of a record in
+		// bytes.
+		RecordSize() int
+	}
+
+	// RecordHeader is the header of a RecordFile: Magic,
+	// followed by Version and the record size as uint32
+	// values in the byte order of the file.
+	RecordHeader struct {
+		Magic   []byte
+		Version uint32
+	}
+
+	// A RecordOption configures a RecordFile.
+	RecordOption func(*recordConfig)
+
+	recordConfig struct {
+		order  binary.ByteOrder
+		header *RecordHeader
+		opts   []Option
+	}
+
+	recordFile[T any] struct {
+		basicFile
+		rmu     sync.Mutex // serializes appends
+		order   binary.ByteOrder
+		size    int   // record size
+		offset  int64 // size of the header
+		version uint32
+	}
+)
+
+// WithByteOrder sets the byte order of the records and
+// header. The default is binary.LittleEndian.
+func WithByteOrder(order binary.ByteOrder) RecordOption {
+	return func(c *recordConfig) { c.order = order }
+}
+
+// WithRecordHeader gives the file a header. It is
+// written when the file is empty and checked when it is
+// opened: the magic, version and record size must all
+// match.
+func WithRecordHeader(h RecordHeader) RecordOption {
+	return func(c *recordConfig) { c.header = &h }
+}
+
+// WithFileOptions sets the options used to open the
+// file. The default is os.O_RDWR|os.O_CREATE.
+func WithFileOptions(opts ...Option) RecordOption {
+	return func(c *recordConfig) { c.opts = append(c.opts, opts...) }
+}
+
+// OpenRecordFile opens the named file as a RecordFile of
+// records of type T, creating it if needed.
+//
+// If there is an error, it will be of type *GoFileError.
+func OpenRecordFile[T any](name string, opts ...RecordOption) (RecordFile[T], error) {
+	const op = "gofile.OpenRecordFile"
+	c := recordConfig{order: binary.LittleEndian}
+	for _, opt := range opts {
+		opt(&c)
+	}
+
+	var zero T
+	d := &recordFile[T]{order: c.order, size: binary.Size(zero)}
+	if d.size <= 0 {
+		return nil, Err(&GoFileError{
+			Op:   prependGoFilePrefix(op),
+			Path: name,
+			Err:  fmt.Errorf("record type %T has no fixed size: %w", zero, ErrInvalid),
+		})
+	}
+	if !settable(reflect.TypeOf(zero)) {
+		return nil, Err(&GoFileError{
+			Op:   prependGoFilePrefix(op),
+			Path: name,
+			Err:  fmt.Errorf("record type %T has unexported fields: %w", zero, ErrInvalid),
+		})
+	}
+
+	fopts := append([]Option{WithFlags(os.O_RDWR | os.O_CREATE)}, c.opts...)
+	d.basicFile.init(name, fopts...)
+
+	// Open the file now, so that Len sees a new file.
+	if _, err := d.rlockFile(); err != nil {
+		return nil, err
+	}
+	d.mu.RUnlock()
+
+	if c.header != nil {
+		if err := d.header(*c.header); err != nil {
+			d.basicFile.Close()
+			return nil, err
+		}
+	}
+	return d, nil
+}
+
+// settable reports whether encoding/binary can decode
+// into every field of t: all struct fields, other than
+// blank ones, must be exported.
+func settable(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Array:
+		return settable(t.Elem())
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if f.Name != "_" && (!f.IsExported() || !settable(f.Type)) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// header writes h to an empty file, or checks that the
+// header of the file matches h.
+func (d *recordFile[T]) header(h RecordHeader) error {
+	const op = "gofile.OpenRecordFile"
+	want := make([]byte, len(h.Magic)+8)
+	copy(want, h.Magic)
+	d.order.PutUint32(want[len(h.Magic):], h.Version)
+	d.order.PutUint32(want[len(h.Magic)+4:], uint32(d.size))
+	d.offset = int64(len(want))
+
+	got := make([]byte, len(want))
+	n, err := d.ReadAt(got, 0)
+	if err != nil && err != io.EOF {
+		return Err(NewGoFileError(op, d.providedName, err))
+	}
+	if n == 0 {
+		if _, err := d.WriteAt(want, 0); err != nil {
+			return Err(NewGoFileError(op, d.providedName, err))
+		}
+		d.basicFile.Dirty()
+		return nil
+	}
+
+	var msg string
+	switch {
+	case n < len(want) || !bytes.Equal(got[:len(h.Magic)], h.Magic):
+		msg = "bad magic"
+	case d.order.Uint32(got[len(h.Magic):]) != h.Version:
+		msg = fmt.Sprintf("version %d, want %d", d.order.Uint32(got[len(h.Magic):]), h.Version)
+	case d.order.Uint32(got[len(h.Magic)+4:]) != uint32(d.size):
+		msg = fmt.Sprintf("record size %d, want %d", d.order.Uint32(got[len(h.Magic)+4:]), d.size)
+	default:
+		return nil
+	}
+	return Err(&GoFileError{
+		Op:   prependGoFilePrefix(op),
+		Path: d.providedName,
+		Err:  fmt.Errorf("record file header: %s: %w", msg, ErrInvalid),
+	})
+}
+
+func (d *recordFile[T]) RecordSize() int { return d.size }
+
+// Len returns the number of whole records. A partly
+// written record at the end of the file is not counted,
+// and is overwritten by the next Append.
+func (d *recordFile[T]) Len() (int, error) {
+	d.basicFile.Dirty()
+	fi, err := d.basicFile.Stat()
+	if err != nil {
+		return 0, err
+	}
+	n := (fi.Size() - d.offset) / int64(d.size)
+	if n < 0 {
+		n = 0
+	}
+	return int(n), nil
+}
+
+func (d *recordFile[T]) Get(i int) (T, error) {
+	var v [1]T
+	_, err := d.GetRange(i, v[:])
+	if err == io.EOF {
+		err = d.indexError("gofile.Get", i, ErrNotExist)
+	}
+	return v[0], err
+}
+
+func (d *recordFile[T]) GetRange(i int, dst []T) (int, error) {
+	const op = "gofile.GetRange"
+	if i < 0 {
+		return 0, d.indexError(op, i, ErrInvalid)
+	}
+
+	b := make([]byte, len(dst)*d.size)
+	n, err := d.ReadAt(b, d.offsetOf(i))
+	if err != nil && err != io.EOF {
+		return 0, Err(NewGoFileError(op, d.providedName, err))
+	}
+
+	n /= d.size
+	if n == 0 && len(dst) > 0 {
+		return 0, io.EOF
+	}
+	if err := binary.Read(bytes.NewReader(b[:n*d.size]), d.order, dst[:n]); err != nil {
+		return 0, Err(NewGoFileError(op, d.providedName, err))
+	}
+	if n < len(dst) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+func (d *recordFile[T]) Put(i int, v T) error {
+	const op = "gofile.Put"
+	d.rmu.Lock()
+	defer d.rmu.Unlock()
+
+	n, err := d.Len()
+	if err != nil {
+		return err
+	}
+	if i < 0 || i > n {
+		return d.indexError(op, i, ErrInvalid)
+	}
+	return d.write(op, i, v)
+}
+
+func (d *recordFile[T]) Append(v ...T) error {
+	d.rmu.Lock()
+	defer d.rmu.Unlock()
+
+	n, err := d.Len()
+	if err != nil {
+		return err
+	}
+	return d.write("gofile.Append", n, v...)
+}
+
+// write encodes v and writes it at record i. The
+// caller must hold d.rmu.
+func (d *recordFile[T]) write(op string, i int, v ...T) error {
+	if len(v) == 0 {
+		return nil
+	}
+	var buf bytes.Buffer
+	buf.Grow(len(v) * d.size)
+	if err := binary.Write(&buf, d.order, v); err != nil {
+		return Err(NewGoFileError(op, d.providedName, err))
+	}
+	if _, err := d.WriteAt(buf.Bytes(), d.offsetOf(i)); err != nil {
+		return Err(NewGoFileError(op, d.providedName, err))
+	}
+	d.basicFile.Dirty()
+	return nil
+}
+
+// indexError returns a *GoFileError for record i.
+func (d *recordFile[T]) indexError(op string, i int, err error) error {
+	return Err(&GoFileError{
+		Op:   prependGoFilePrefix(op),
+		Path: d.providedName,
+		Err:  fmt.Errorf("record %d: %w", i, err),
+	})
+}
+
+// offsetOf returns the offset of record i in the file.
+func (d *recordFile[T]) offsetOf(i int) int64 {
+	return d.offset + int64(i)*int64(d.size)
+}
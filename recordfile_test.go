@@ -0,0 +1,112 @@
+package basicfile
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type testRecord struct {
+	ID    uint32
+	Temp  float32
+	Flags [2]byte
+	_     [2]byte
+}
+
+func TestRecordFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "readings.bin")
+	hdr := WithRecordHeader(RecordHeader{Magic: []byte("RDNG"), Version: 2})
+
+	f, err := OpenRecordFile[testRecord](path, hdr, WithByteOrder(binary.BigEndian))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.RecordSize() != 12 {
+		t.Errorf("RecordSize() = %d, want 12", f.RecordSize())
+	}
+	if err := f.Append(testRecord{ID: 1, Temp: 20.5}, testRecord{ID: 2}, testRecord{ID: 3}); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Put(1, testRecord{ID: 2, Temp: -4, Flags: [2]byte{1, 0}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Put(3, testRecord{ID: 4}); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Put(9, testRecord{}); !errors.Is(err, ErrInvalid) {
+		t.Errorf("Put(9) = %v, want ErrInvalid", err)
+	}
+	f.Close()
+
+	if fi, _ := os.Stat(path); fi.Size() != 12+4*12 {
+		t.Errorf("file size = %d, want %d", fi.Size(), 12+4*12)
+	}
+
+	f, err = OpenRecordFile[testRecord](path, hdr, WithByteOrder(binary.BigEndian), WithFileOptions(WithReadOnly()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	if n, err := f.Len(); n != 4 || err != nil {
+		t.Errorf("Len() = %d, %v, want 4", n, err)
+	}
+	if r, err := f.Get(1); err != nil || r.Temp != -4 || r.Flags[0] != 1 {
+		t.Errorf("Get(1) = %+v, %v", r, err)
+	}
+	if _, err := f.Get(4); !errors.Is(err, ErrNotExist) {
+		t.Errorf("Get(4) = %v, want ErrNotExist", err)
+	}
+
+	dst := make([]testRecord, 3)
+	n, err := f.GetRange(2, dst)
+	if n != 2 || err != io.EOF || dst[0].ID != 3 || dst[1].ID != 4 {
+		t.Errorf("GetRange(2) = %d, %v, %+v", n, err, dst)
+	}
+
+	for _, opt := range []RecordOption{
+		WithRecordHeader(RecordHeader{Magic: []byte("XXXX"), Version: 2}),
+		WithRecordHeader(RecordHeader{Magic: []byte("RDNG"), Version: 3}),
+	} {
+		if _, err := OpenRecordFile[testRecord](path, opt, WithByteOrder(binary.BigEndian)); !errors.Is(err, ErrInvalid) {
+			t.Errorf("OpenRecordFile(bad header) = %v, want ErrInvalid", err)
+		}
+	}
+	if _, err := OpenRecordFile[uint64](path, hdr, WithByteOrder(binary.BigEndian)); !errors.Is(err, ErrInvalid) {
+		t.Errorf("OpenRecordFile(wrong record size) = %v, want ErrInvalid", err)
+	}
+	if _, err := OpenRecordFile[string](path); !errors.Is(err, ErrInvalid) {
+		t.Errorf("OpenRecordFile[string] = %v, want ErrInvalid", err)
+	}
+	if _, err := OpenRecordFile[struct{ a int32 }](path); !errors.Is(err, ErrInvalid) {
+		t.Errorf("OpenRecordFile[struct{ a int32 }] = %v, want ErrInvalid", err)
+	}
+
+	var gfe *GoFileError
+	if _, err := f.GetRange(-1, dst); !errors.As(err, &gfe) || gfe.Path != path || !errors.Is(err, ErrInvalid) {
+		t.Errorf("GetRange(-1) = %v, want a *GoFileError for %s wrapping ErrInvalid", err, path)
+	}
+}
+
+func TestRecordFileNew(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "new.bin")
+
+	f, err := OpenRecordFile[int64](path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	if n, err := f.Len(); n != 0 || err != nil {
+		t.Errorf("Len() of a new file = %d, %v, want 0", n, err)
+	}
+	if err := f.Append(7, 8); err != nil {
+		t.Fatal(err)
+	}
+	if v, err := f.Get(1); v != 8 || err != nil {
+		t.Errorf("Get(1) = %d, %v, want 8", v, err)
+	}
+}
@@ -0,0 +1,95 @@
+package basicfile
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// ColumnType is the type of the values in a column of a
+// record file.
+type ColumnType int
+
+// Column types.
+const (
+	ColumnString ColumnType = iota
+	ColumnInt
+	ColumnFloat
+	ColumnBool
+	ColumnTime
+)
+
+type (
+	// Column describes a column of a record file.
+	Column struct {
+		Name     string
+		Type     ColumnType
+		Required bool   // the value may not be empty
+		Layout   string // time layout for ColumnTime; default time.RFC3339
+	}
+
+	// Schema describes the columns of a record file.
+	Schema []Column
+
+	// FieldError records the position of a value in a
+	// record file that is missing or cannot be parsed.
+	FieldError struct {
+		Row    int    // 1-based row number, not counting any header
+		Line   int    // 1-based line number in the file
+		Column string // column name
+		Err    error
+	}
+)
+
+// ErrMissingValue is matched by errors for required
+// values that are empty.
+var ErrMissingValue = NewGoFileError("missing required value", "", ErrInvalid)
+
+func (e *FieldError) Error() string {
+	return fmt.Sprintf("row %d (line %d), column %q: %v", e.Row, e.Line, e.Column, e.Err)
+}
+
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
+// fieldError returns a *GoFileError for a FieldError.
+func fieldError(op, path string, e *FieldError) error {
+	return &GoFileError{
+		Op:   prependGoFilePrefix(op),
+		Path: path,
+		Err:  e,
+	}
+}
+
+// check parses s as a value of column c. An empty value
+// is an error only if c is required.
+func (c Column) check(s string) error {
+	if s == "" {
+		if c.Required {
+			return ErrMissingValue
+		}
+		return nil
+	}
+	_, err := c.parse(s)
+	return err
+}
+
+// parse returns s as a value of the type of c.
+func (c Column) parse(s string) (interface{}, error) {
+	switch c.Type {
+	case ColumnInt:
+		return strconv.ParseInt(s, 10, 64)
+	case ColumnFloat:
+		return strconv.ParseFloat(s, 64)
+	case ColumnBool:
+		return strconv.ParseBool(s)
+	case ColumnTime:
+		layout := c.Layout
+		if layout == "" {
+			layout = time.RFC3339
+		}
+		return time.Parse(layout, s)
+	}
+	return s, nil
+}
@@ -0,0 +1,284 @@
+package basicfile
+
+import (
+	"container/list"
+	"fmt"
+	"io"
+	"sync"
+)
+
+const (
+	defaultPageSize = 1 << 16 // 64 KiB
+	defaultMaxAlloc = 1 << 26 // 64 MiB
+)
+
+type (
+	// Options configures a section cached file.
+	Options struct {
+		// MaxAlloc is the maximum number of bytes of
+		// file data held in memory at once. It is
+		// rounded down to a whole number of pages,
+		// but at least one page is always cached.
+		MaxAlloc int64
+
+		// PageSize is the size of each cached
+		// section of the file.
+		PageSize int
+	}
+
+	// sectionFile is a read-only BasicFile for files that
+	// are too large to fit in memory. The file is divided
+	// into pages of PageSize bytes and at most MaxAlloc
+	// bytes of pages are held in a least recently used
+	// cache. Pages are read from disk on demand.
+	sectionFile struct {
+		basicFile
+		pageSize int64
+		maxPages int
+
+		cmu   sync.Mutex              // guards the fields below
+		pages map[int64]*list.Element // page index -> lru element
+		lru   *list.List              // front is most recently used
+		size  int64                   // file size when the cache was filled
+		off   int64                   // offset for Read and Seek
+	}
+
+	// page is a cached section of the file.
+	page struct {
+		index int64
+		data  []byte
+	}
+)
+
+// NewSectionCachedFile opens the named file for reading
+// and returns a BasicFile that serves Read, ReadAt and
+// Seek from a cache of file sections (pages), reading
+// pages from disk as they are needed.
+//
+// It is intended for files too large to fit in memory.
+// Zero values in opts are replaced with defaults.
+//
+// If there is an error, it will be of type *GoFileError.
+func NewSectionCachedFile(name string, opts Options) (BasicFile, error) {
+	if opts.PageSize <= 0 {
+		opts.PageSize = defaultPageSize
+	}
+	if opts.MaxAlloc <= 0 {
+		opts.MaxAlloc = defaultMaxAlloc
+	}
+	maxPages := int(opts.MaxAlloc / int64(opts.PageSize))
+	if maxPages < 1 {
+		maxPages = 1
+	}
+
+	f := &sectionFile{
+		pageSize: int64(opts.PageSize),
+		maxPages: maxPages,
+		pages:    make(map[int64]*list.Element, maxPages),
+		lru:      list.New(),
+	}
+	f.basicFile.init(name, WithReadOnly())
+
+	err := f.basicFile.Open()
+	if err != nil {
+		return nil, err
+	}
+
+	fi, err := f.basicFile.Stat()
+	if err != nil {
+		return nil, err
+	}
+	f.size = fi.Size()
+
+	return f, nil
+}
+
+// Dirty discards all cached pages, forcing them to be
+// read again from disk.
+func (f *sectionFile) Dirty() {
+	f.basicFile.Dirty()
+
+	f.cmu.Lock()
+	defer f.cmu.Unlock()
+	f.purge()
+}
+
+// Read reads up to len(b) bytes from the current offset.
+func (f *sectionFile) Read(b []byte) (n int, err error) {
+	f.cmu.Lock()
+	defer f.cmu.Unlock()
+
+	n, err = f.readAt(b, f.off)
+	f.off += int64(n)
+	if err == io.EOF && n > 0 {
+		err = nil
+	}
+	return n, err
+}
+
+// ReadAt reads len(b) bytes starting at byte offset off.
+// It returns io.EOF if fewer than len(b) bytes remain.
+func (f *sectionFile) ReadAt(b []byte, off int64) (n int, err error) {
+	if off < 0 {
+		return 0, NewGoFileError("gofile.ReadAt", f.providedName, ErrInvalid)
+	}
+
+	f.cmu.Lock()
+	defer f.cmu.Unlock()
+	return f.readAt(b, off)
+}
+
+// Seek sets the offset for the next Read, interpreted
+// according to whence.
+func (f *sectionFile) Seek(offset int64, whence int) (int64, error) {
+	f.cmu.Lock()
+	defer f.cmu.Unlock()
+
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += f.off
+	case io.SeekEnd:
+		offset += f.size
+	default:
+		return 0, NewGoFileError("gofile.Seek", f.providedName, ErrInvalid)
+	}
+	if offset < 0 {
+		return 0, NewGoFileError("gofile.Seek", f.providedName, ErrInvalid)
+	}
+	f.off = offset
+	return offset, nil
+}
+
+// WriteTo writes the file from the current offset to
+// w through the cache and advances the offset.
+func (f *sectionFile) WriteTo(w io.Writer) (n int64, err error) {
+	f.cmu.Lock()
+	defer f.cmu.Unlock()
+
+	buf := make([]byte, f.pageSize)
+	for {
+		m, err := f.readAt(buf, f.off)
+		if m > 0 {
+			c, werr := w.Write(buf[:m])
+			n += int64(c)
+			f.off += int64(c)
+			if werr != nil {
+				return n, werr
+			}
+		}
+		if err == io.EOF {
+			return n, nil
+		}
+		if err != nil {
+			return n, err
+		}
+	}
+}
+
+// Write fails: the file is read-only.
+func (f *sectionFile) Write(b []byte) (int, error) {
+	return 0, f.readOnly("gofile.Write")
+}
+
+// WriteAt fails: the file is read-only.
+func (f *sectionFile) WriteAt(b []byte, off int64) (int, error) {
+	return 0, f.readOnly("gofile.WriteAt")
+}
+
+// WriteString fails: the file is read-only.
+func (f *sectionFile) WriteString(s string) (int, error) {
+	return 0, f.readOnly("gofile.WriteString")
+}
+
+// ReadFrom fails: the file is read-only.
+func (f *sectionFile) ReadFrom(r io.Reader) (int64, error) {
+	return 0, f.readOnly("gofile.ReadFrom")
+}
+
+// Truncate fails: the file is read-only.
+func (f *sectionFile) Truncate(size int64) error {
+	return f.readOnly("gofile.Truncate")
+}
+
+func (f *sectionFile) readOnly(op string) error {
+	return Err(&GoFileError{
+		Op:   prependGoFilePrefix(op),
+		Path: f.providedName,
+		Err:  fmt.Errorf("section cached file is read-only: %w", ErrPermission),
+	})
+}
+
+// Close discards the cache and closes the underlying file.
+func (f *sectionFile) Close() error {
+	f.cmu.Lock()
+	f.purge()
+	f.cmu.Unlock()
+	return f.basicFile.Close()
+}
+
+// readAt copies file data starting at off into b, one
+// page at a time. The caller must hold f.cmu.
+func (f *sectionFile) readAt(b []byte, off int64) (n int, err error) {
+	for n < len(b) {
+		if off >= f.size {
+			return n, io.EOF
+		}
+
+		p, err := f.page(off / f.pageSize)
+		if err != nil {
+			return n, err
+		}
+
+		start := off % f.pageSize
+		if start >= int64(len(p.data)) {
+			return n, io.EOF
+		}
+		c := copy(b[n:], p.data[start:])
+		n += c
+		off += int64(c)
+	}
+	return n, nil
+}
+
+// page returns the page with the given index, reading it
+// from disk if it is not cached. The least recently used
+// page is evicted if the cache is full. The caller must
+// hold f.cmu.
+func (f *sectionFile) page(index int64) (*page, error) {
+	if e, ok := f.pages[index]; ok {
+		f.lru.MoveToFront(e)
+		return e.Value.(*page), nil
+	}
+
+	var p *page
+	if f.lru.Len() >= f.maxPages {
+		e := f.lru.Back()
+		p = f.lru.Remove(e).(*page)
+		delete(f.pages, p.index)
+		p.index = index
+		p.data = p.data[:cap(p.data)]
+	} else {
+		p = &page{index: index, data: make([]byte, f.pageSize)}
+	}
+
+	n, err := f.basicFile.ReadAt(p.data, index*f.pageSize)
+	if err != nil && err != io.EOF {
+		return nil, Err(NewGoFileError("gofile.page", f.providedName, err))
+	}
+	p.data = p.data[:n]
+
+	f.pages[index] = f.lru.PushFront(p)
+	return p, nil
+}
+
+// purge discards all cached pages and refreshes the
+// cached file size. The caller must hold f.cmu.
+func (f *sectionFile) purge() {
+	f.lru.Init()
+	f.pages = make(map[int64]*list.Element, f.maxPages)
+
+	if fi, err := f.basicFile.Stat(); err == nil {
+		f.size = fi.Size()
+	}
+}
@@ -0,0 +1,73 @@
+package basicfile
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestNewSectionCachedFile(t *testing.T) {
+	want := bytes.Repeat([]byte("0123456789abcdef"), 1000) // 16000 bytes
+	name := filepath.Join(t.TempDir(), "large.dat")
+	if err := os.WriteFile(name, want, NormalMode); err != nil {
+		t.Fatal(err)
+	}
+
+	bf, err := NewSectionCachedFile(name, Options{MaxAlloc: 3000, PageSize: 1000})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer bf.Close()
+	f := bf.(*sectionFile)
+
+	got, err := io.ReadAll(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("ReadAll() returned %d bytes, want %d", len(got), len(want))
+	}
+	if f.lru.Len() > 3 {
+		t.Errorf("cached pages = %d, want <= 3", f.lru.Len())
+	}
+
+	buf := make([]byte, 2500)
+	if _, err := f.ReadAt(buf, 7000); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf, want[7000:9500]) {
+		t.Error("ReadAt() across page boundaries returned wrong data")
+	}
+
+	if _, err := f.Seek(-10, io.SeekEnd); err != nil {
+		t.Fatal(err)
+	}
+	n, err := f.Read(buf)
+	if n != 10 || err != nil {
+		t.Errorf("Read() at end = %d, %v; want 10, nil", n, err)
+	}
+	if _, err := f.Read(buf); err != io.EOF {
+		t.Errorf("Read() past end = %v, want io.EOF", err)
+	}
+
+	if _, err := f.Seek(15995, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	if _, err := io.Copy(&out, f); err != nil || out.String() != "bcdef" {
+		t.Errorf("io.Copy after Seek = %q, %v, want %q", out.String(), err, "bcdef")
+	}
+
+	if _, err := f.Write([]byte("x")); !errors.Is(err, ErrPermission) {
+		t.Errorf("Write() = %v, want ErrPermission", err)
+	}
+	if _, err := f.WriteAt([]byte("x"), 0); !errors.Is(err, ErrPermission) {
+		t.Errorf("WriteAt() = %v, want ErrPermission", err)
+	}
+	if err := f.Truncate(0); !errors.Is(err, ErrPermission) {
+		t.Errorf("Truncate() = %v, want ErrPermission", err)
+	}
+}
@@ -0,0 +1,242 @@
+package basicfile
+
+import "io"
+
+// defaultBufSize is the default size of each buffer
+// of a BufferedSectionWriter.
+const defaultBufSize = 1 << 16 // 64 KiB
+
+type (
+	// BufferedSectionWriter converts incoming Write()
+	// requests into buffered, asynchronous WriteAt()'s
+	// in a section of a file.
+	//
+	// Two buffers are used: while one is being written
+	// to the file in the background, the other collects
+	// new data. Several writers may write independent
+	// sections of the same (preallocated) file in
+	// parallel.
+	//
+	// A BufferedSectionWriter is not safe for concurrent
+	// use by multiple goroutines.
+	//
+	// Reference: https://github.com/couchbase/moss
+	BufferedSectionWriter struct {
+		err error
+		w   io.WriterAt
+		beg int64 // start position of the section in the file
+		cur int64 // position of the next WriteAt in the file
+		max int64 // when > 0, max number of bytes that may be written
+
+		buf    []byte // buffer collecting new data
+		buflen int    // number of bytes used in buf
+
+		res     ioBuf // result of the last background write
+		pending bool  // res has been received but not used
+		waiting bool  // a result has not been used by result
+
+		doneCh chan struct{}
+		reqCh  chan ioBuf
+		resCh  chan ioBuf
+	}
+
+	// ioBuf is a buffer to be written at pos, or the
+	// result of writing it.
+	ioBuf struct {
+		buf []byte
+		pos int64
+		err error
+	}
+)
+
+// NewBufferedSectionWriter converts incoming Write() requests into
+// buffered, asynchronous WriteAt()'s in a section of a file.
+//
+// Writing starts at begPos. If maxBytes > 0, writes that
+// would extend past begPos+maxBytes fail with ErrShortWrite.
+// Two buffers of bufSize bytes are used.
+//
+// Stop (or Close) must be called to write any buffered
+// data and release the background goroutine.
+//
+// Reference: https://github.com/couchbase/moss
+func NewBufferedSectionWriter(w io.WriterAt, begPos, maxBytes int64,
+	bufSize int) *BufferedSectionWriter {
+	if bufSize <= 0 {
+		bufSize = defaultBufSize
+	}
+
+	doneCh := make(chan struct{})
+	reqCh := make(chan ioBuf)
+	resCh := make(chan ioBuf)
+
+	go func() {
+		defer close(doneCh)
+		defer close(resCh)
+
+		buf := make([]byte, bufSize)
+		var pos int64
+		var err error
+
+		for {
+			resCh <- ioBuf{buf: buf, pos: pos, err: err}
+
+			req, ok := <-reqCh
+			if !ok {
+				return
+			}
+			buf, pos = req.buf, req.pos
+			if len(buf) > 0 {
+				var n int
+				n, err = w.WriteAt(buf, pos)
+				if err == nil && n < len(buf) {
+					err = ErrShortWrite
+				}
+			}
+		}
+	}()
+
+	return &BufferedSectionWriter{
+		w:   w,
+		beg: begPos,
+		cur: begPos,
+		max: maxBytes,
+		buf: make([]byte, bufSize),
+
+		waiting: true, // the goroutine starts by sending its buffer
+
+		doneCh: doneCh,
+		reqCh:  reqCh,
+		resCh:  resCh,
+	}
+}
+
+// Offset returns the file position following the
+// last byte accepted by Write.
+func (b *BufferedSectionWriter) Offset() int64 {
+	return b.cur + int64(b.buflen)
+}
+
+// Written returns the number of bytes accepted by
+// Write.
+func (b *BufferedSectionWriter) Written() int64 {
+	return b.Offset() - b.beg
+}
+
+// Write copies p into the buffer, handing full buffers
+// to the background writer.
+//
+// If a previous background write failed, its error is
+// returned and no data is accepted. If p would extend
+// the section past its maximum size, no data is
+// accepted and ErrShortWrite is returned. After Stop,
+// ErrClosed is returned.
+func (b *BufferedSectionWriter) Write(p []byte) (nn int, err error) {
+	if b.reqCh == nil {
+		return 0, ErrClosed
+	}
+	b.poll()
+	if b.err != nil {
+		return 0, b.err
+	}
+	if b.max > 0 && b.Written()+int64(len(p)) > b.max {
+		return 0, ErrShortWrite
+	}
+
+	for len(p) > 0 && b.err == nil {
+		n := copy(b.buf[b.buflen:], p)
+		b.buflen += n
+		if n < len(p) {
+			b.err = b.Flush()
+		}
+		nn += n
+		p = p[n:]
+	}
+	return nn, b.err
+}
+
+// Flush hands the buffered data to the background
+// writer, waiting for the previous background write
+// to complete first. It does not wait for the data
+// to be written; use Stop for that.
+func (b *BufferedSectionWriter) Flush() error {
+	if b.err != nil {
+		return b.err
+	}
+	if b.buflen <= 0 {
+		return nil
+	}
+
+	prevWrite := b.result()
+	b.err = prevWrite.err
+	if b.err != nil {
+		return b.err
+	}
+
+	b.reqCh <- ioBuf{buf: b.buf[0:b.buflen], pos: b.cur}
+	b.waiting = true
+
+	b.cur += int64(b.buflen)
+	b.buf = prevWrite.buf[:cap(prevWrite.buf)]
+	b.buflen = 0
+
+	return nil
+}
+
+// Stop writes any buffered data, waits for all
+// background writes to complete and stops the
+// background goroutine. It returns the first error
+// encountered. Stop may be called more than once.
+func (b *BufferedSectionWriter) Stop() error {
+	if b.reqCh == nil {
+		return b.err
+	}
+
+	b.Flush()
+
+	if b.waiting {
+		last := b.result()
+		if b.err == nil {
+			b.err = last.err
+		}
+	}
+
+	close(b.reqCh)
+	<-b.doneCh
+	b.reqCh = nil
+
+	return b.err
+}
+
+// Close is an alias of Stop that implements io.Closer.
+func (b *BufferedSectionWriter) Close() error {
+	return b.Stop()
+}
+
+// poll records the result of a completed background
+// write, if there is one, without blocking.
+func (b *BufferedSectionWriter) poll() {
+	if b.pending || !b.waiting || b.reqCh == nil {
+		return
+	}
+	select {
+	case b.res = <-b.resCh:
+		b.pending = true
+		if b.err == nil {
+			b.err = b.res.err
+		}
+	default:
+	}
+}
+
+// result returns the result of the last background
+// write, waiting for it to complete if needed. It must
+// only be called when b.waiting is true.
+func (b *BufferedSectionWriter) result() ioBuf {
+	b.waiting = false
+	if b.pending {
+		b.pending = false
+		return b.res
+	}
+	return <-b.resCh
+}
@@ -0,0 +1,86 @@
+package basicfile
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestBufferedSectionWriter(t *testing.T) {
+	const sections, size = 4, 10000
+
+	name := filepath.Join(t.TempDir(), "sections.dat")
+	bf, err := Create(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := bf.(*basicFile)
+	if err := f.Truncate(sections * size); err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < sections; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			w := NewBufferedSectionWriter(f, int64(i*size), size, 64)
+			chunk := bytes.Repeat([]byte{'a' + byte(i)}, 100)
+			for n := 0; n < size; n += len(chunk) {
+				if _, err := w.Write(chunk); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+			if _, err := w.Write([]byte{'x'}); !errors.Is(err, ErrShortWrite) {
+				t.Errorf("Write() past section = %v, want ErrShortWrite", err)
+			}
+			if err := w.Stop(); err != nil {
+				t.Error(err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	got, err := os.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < sections; i++ {
+		want := bytes.Repeat([]byte{'a' + byte(i)}, size)
+		if !bytes.Equal(got[i*size:(i+1)*size], want) {
+			t.Errorf("section %d has wrong contents", i)
+		}
+	}
+}
+
+// failingWriterAt fails every WriteAt after a delay.
+type failingWriterAt struct{}
+
+func (failingWriterAt) WriteAt(p []byte, off int64) (int, error) {
+	time.Sleep(10 * time.Millisecond)
+	return 0, ErrShortWrite
+}
+
+func TestBufferedSectionWriterStopAfterError(t *testing.T) {
+	w := NewBufferedSectionWriter(failingWriterAt{}, 0, 0, 4)
+	w.Write([]byte("12345"))
+	if _, err := w.Write([]byte("67890abc")); !errors.Is(err, ErrShortWrite) {
+		t.Errorf("Write() after a failed write = %v, want ErrShortWrite", err)
+	}
+
+	done := make(chan error)
+	go func() { done <- w.Stop() }()
+	select {
+	case err := <-done:
+		if !errors.Is(err, ErrShortWrite) {
+			t.Errorf("Stop() = %v, want ErrShortWrite", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Stop() did not return")
+	}
+}
@@ -0,0 +1,553 @@
+package basicfile
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// ShapeType is the geometry type of an Esri Shapefile
+// and its records.
+type ShapeType int32
+
+// Shape types of the Esri Shapefile format.
+const (
+	ShapeNull        ShapeType = 0
+	ShapePoint       ShapeType = 1
+	ShapePolyLine    ShapeType = 3
+	ShapePolygon     ShapeType = 5
+	ShapeMultiPoint  ShapeType = 8
+	ShapePointZ      ShapeType = 11
+	ShapePolyLineZ   ShapeType = 13
+	ShapePolygonZ    ShapeType = 15
+	ShapeMultiPointZ ShapeType = 18
+	ShapePointM      ShapeType = 21
+	ShapePolyLineM   ShapeType = 23
+	ShapePolygonM    ShapeType = 25
+	ShapeMultiPointM ShapeType = 28
+	ShapeMultiPatch  ShapeType = 31
+)
+
+var shapeTypeNames = map[ShapeType]string{
+	ShapeNull:        "Null",
+	ShapePoint:       "Point",
+	ShapePolyLine:    "PolyLine",
+	ShapePolygon:     "Polygon",
+	ShapeMultiPoint:  "MultiPoint",
+	ShapePointZ:      "PointZ",
+	ShapePolyLineZ:   "PolyLineZ",
+	ShapePolygonZ:    "PolygonZ",
+	ShapeMultiPointZ: "MultiPointZ",
+	ShapePointM:      "PointM",
+	ShapePolyLineM:   "PolyLineM",
+	ShapePolygonM:    "PolygonM",
+	ShapeMultiPointM: "MultiPointM",
+	ShapeMultiPatch:  "MultiPatch",
+}
+
+func (t ShapeType) String() string {
+	if s, ok := shapeTypeNames[t]; ok {
+		return s
+	}
+	return fmt.Sprintf("ShapeType(%d)", int32(t))
+}
+
+// base returns the 2D type of t: ShapePoint for
+// ShapePointZ and ShapePointM, and so on.
+func (t ShapeType) base() ShapeType {
+	switch {
+	case t > 20 && t < 30:
+		return t - 20
+	case t > 10 && t < 20:
+		return t - 10
+	}
+	return t
+}
+
+// hasZ reports whether shapes of type t have Z values.
+func (t ShapeType) hasZ() bool { return t > 10 && t < 20 || t == ShapeMultiPatch }
+
+// hasM reports whether shapes of type t may have M
+// values.
+func (t ShapeType) hasM() bool { return t > 10 && t < 30 || t == ShapeMultiPatch }
+
+type (
+	// Shapefile is an Esri Shapefile: the geometry in a
+	// .shp file, its index in a .shx file and the
+	// attributes of each shape in a .dbf file, all with
+	// the same name apart from the extension.
+	Shapefile interface {
+		BasicFile
+
+		// ShapeType returns the type of the shapes in
+		// the file.
+		ShapeType() ShapeType
+
+		// Bounds returns the bounding box of the shapes
+		// in the file.
+		Bounds() Box
+
+		// Len returns the number of records.
+		Len() int
+
+		// Fields returns the attribute fields of the
+		// .dbf file, or nil if there is none.
+		Fields() []DBFField
+
+		// Record returns record n (1-based).
+		Record(n int) (ShapeRecord, error)
+
+		// Records returns an iterator over the records
+		// of the file, in order.
+		Records() ShapeIterator
+	}
+
+	// ShapeIterator iterates over the records of a
+	// Shapefile.
+	//
+	//  it := f.Records()
+	//  defer it.Close()
+	//  for it.Next() {
+	//  	r := it.Record()
+	//  	fmt.Println(r.Num, r.Shape.Type, r.Attrs["NAME"])
+	//  }
+	//  if err := it.Err(); err != nil { ... }
+	ShapeIterator interface {
+		io.Closer
+
+		// Next advances to the next record. It returns
+		// false after the last record or on error.
+		Next() bool
+
+		// Record returns the current record.
+		Record() ShapeRecord
+
+		// Err returns the first error encountered by
+		// Next.
+		Err() error
+	}
+
+	// Point is a point of a Shape. Z is only set for
+	// the Z shape types, and M for the Z and M shape
+	// types that have measures.
+	Point struct {
+		X, Y, Z, M float64
+	}
+
+	// Box is a bounding box.
+	Box struct {
+		MinX, MinY, MaxX, MaxY float64
+	}
+
+	// Shape is the geometry of a Shapefile record.
+	// Points holds the points of every part, and Parts
+	// the index in Points of the first point of each
+	// part: the lines of a polyline or the rings of a
+	// polygon. Points and multipoints have one part.
+	Shape struct {
+		Type   ShapeType
+		Box    Box
+		Parts  []int
+		Points []Point
+	}
+
+	// ShapeRecord is a record of a Shapefile.
+	ShapeRecord struct {
+		Num   int // 1-based record number
+		Shape Shape
+
+		// Attrs holds the attributes of the record from
+		// the .dbf file, by field name, or nil if there
+		// is no .dbf file. See DBFField for the types of
+		// the values.
+		Attrs map[string]interface{}
+
+		// Deleted reports whether the record is marked
+		// as deleted in the .dbf file.
+		Deleted bool
+	}
+
+	shapefile struct {
+		basicFile
+		smu       sync.Mutex // guards dbf
+		shapeType ShapeType
+		bounds    Box
+		size      int64   // size of the .shp file when it was opened
+		offsets   []int64 // offset of each record header in the .shp file
+		lengths   []int64 // content length of each record, in bytes
+		dbf       *dbfFile
+	}
+
+	shapeIter struct {
+		f   *shapefile
+		n   int
+		rec ShapeRecord
+		err error
+	}
+)
+
+const (
+	shpHeaderSize   = 100
+	shpFileCode     = 9994
+	shpRecordHeader = 8
+)
+
+// Part returns the points of part i of s, or nil if s
+// has no part i. A ShapeNull shape has no parts.
+func (s Shape) Part(i int) []Point {
+	if i < 0 || i >= len(s.Parts) {
+		return nil
+	}
+	end := len(s.Points)
+	if i+1 < len(s.Parts) {
+		end = s.Parts[i+1]
+	}
+	return s.Points[s.Parts[i]:end]
+}
+
+// OpenShapefile opens an Esri Shapefile. name may be the
+// path of its .shp, .shx or .dbf file, or the path
+// without an extension; the other files are found
+// beside it, with an extension in the same case.
+//
+// The .shp file is required. Without the .shx file, the
+// .shp file is scanned to find each record; without the
+// .dbf file, records have no attributes. Each attribute
+// is decoded as UTF-8 if it is valid UTF-8, and
+// otherwise as Windows-1252, or as Latin-1 if it has a
+// byte that Windows-1252 does not define.
+//
+// Z and M values are read for the Z and M shape types.
+// MultiPatch records are read as their points and parts;
+// the part types are not returned.
+//
+// If there is an error, it will be of type *GoFileError.
+func OpenShapefile(name string) (Shapefile, error) {
+	d := &shapefile{}
+	d.basicFile.init(shapefileSibling(name, ".shp"), WithReadOnly())
+
+	if err := d.readHeader(); err != nil {
+		d.basicFile.Close()
+		return nil, err
+	}
+	if err := d.readIndex(); err != nil {
+		d.basicFile.Close()
+		return nil, err
+	}
+
+	dbf, err := openDBF(d.sibling(".dbf"))
+	if err != nil && !os.IsNotExist(err) {
+		d.basicFile.Close()
+		return nil, Err(NewGoFileError("gofile.OpenShapefile", d.sibling(".dbf"), err))
+	}
+	d.dbf = dbf
+	return d, nil
+}
+
+// shapefileSibling returns the path of the file of
+// the Shapefile named name with extension ext. The
+// extension is upper case if that of name is.
+func shapefileSibling(name, ext string) string {
+	e := filepath.Ext(name)
+	switch strings.ToLower(e) {
+	case ".shp", ".shx", ".dbf":
+		name = strings.TrimSuffix(name, e)
+		if e == strings.ToUpper(e) {
+			ext = strings.ToUpper(ext)
+		}
+	}
+	return name + ext
+}
+
+// sibling returns the path of the file of the Shapefile
+// with extension ext, using Dir, Base and Ext.
+func (d *shapefile) sibling(ext string) string {
+	if d.Ext() == strings.ToUpper(d.Ext()) {
+		ext = strings.ToUpper(ext)
+	}
+	return filepath.Join(d.Dir(), strings.TrimSuffix(d.Base(), d.Ext())+ext)
+}
+
+// readHeader reads the main file header of the .shp
+// file.
+func (d *shapefile) readHeader() error {
+	var h [shpHeaderSize]byte
+	if _, err := d.ReadAt(h[:], 0); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return Err(NewGoFileError("gofile.OpenShapefile", d.providedName, err))
+	}
+	if binary.BigEndian.Uint32(h[0:]) != shpFileCode {
+		return d.formatError("gofile.OpenShapefile", fmt.Errorf("shapefile: bad file code"))
+	}
+	d.shapeType = ShapeType(binary.LittleEndian.Uint32(h[32:]))
+	d.bounds = readBox(h[36:])
+
+	fi, err := d.basicFile.Stat()
+	if err != nil {
+		return err
+	}
+	d.size = fi.Size()
+	return nil
+}
+
+// readIndex reads the record offsets from the .shx file
+// or, if there is none, by scanning the .shp file.
+func (d *shapefile) readIndex() error {
+	b, err := os.ReadFile(d.sibling(".shx"))
+	if os.IsNotExist(err) {
+		return d.scanIndex()
+	}
+	if err != nil {
+		return Err(NewGoFileError("gofile.OpenShapefile", d.sibling(".shx"), err))
+	}
+	if len(b) < shpHeaderSize || binary.BigEndian.Uint32(b) != shpFileCode {
+		return Err(&GoFileError{
+			Op:   prependGoFilePrefix("gofile.OpenShapefile"),
+			Path: d.sibling(".shx"),
+			Err:  fmt.Errorf("shapefile index: bad header: %w", ErrInvalid),
+		})
+	}
+
+	b = b[shpHeaderSize:]
+	n := len(b) / 8
+	d.offsets = make([]int64, n)
+	d.lengths = make([]int64, n)
+	for i := range d.offsets {
+		d.offsets[i] = 2 * int64(binary.BigEndian.Uint32(b[8*i:]))
+		d.lengths[i] = 2 * int64(binary.BigEndian.Uint32(b[8*i+4:]))
+	}
+	return nil
+}
+
+// scanIndex finds the record offsets by reading each
+// record header of the .shp file.
+func (d *shapefile) scanIndex() error {
+	size := d.size
+	var h [shpRecordHeader]byte
+	for off := int64(shpHeaderSize); off+shpRecordHeader <= size; {
+		if _, err := d.ReadAt(h[:], off); err != nil {
+			return Err(NewGoFileError("gofile.OpenShapefile", d.providedName, err))
+		}
+		length := 2 * int64(binary.BigEndian.Uint32(h[4:]))
+		d.offsets = append(d.offsets, off)
+		d.lengths = append(d.lengths, length)
+		off += shpRecordHeader + length
+	}
+	return nil
+}
+
+func (d *shapefile) ShapeType() ShapeType { return d.shapeType }
+func (d *shapefile) Bounds() Box          { return d.bounds }
+func (d *shapefile) Len() int             { return len(d.offsets) }
+
+func (d *shapefile) Fields() []DBFField {
+	if d.dbf == nil {
+		return nil
+	}
+	return d.dbf.fields
+}
+
+// Record returns record n (1-based), read using the
+// offsets in the .shx file, with its attributes.
+func (d *shapefile) Record(n int) (ShapeRecord, error) {
+	const op = "gofile.Record"
+	if n < 1 || n > len(d.offsets) {
+		return ShapeRecord{}, NewGoFileError(op, d.providedName, ErrInvalid)
+	}
+
+	// The length comes from the .shx file; check it
+	// against the .shp file before allocating.
+	off, length := d.offsets[n-1]+shpRecordHeader, d.lengths[n-1]
+	if off > d.size || length > d.size-off {
+		return ShapeRecord{}, d.formatError(op, fmt.Errorf("shapefile record %d: extends past the end of the file: %w", n, ErrInvalid))
+	}
+
+	b := make([]byte, length)
+	if _, err := d.ReadAt(b, off); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return ShapeRecord{}, Err(NewGoFileError(op, d.providedName, err))
+	}
+	shape, err := parseShape(b)
+	if err != nil {
+		return ShapeRecord{}, d.formatError(op, fmt.Errorf("shapefile record %d: %w", n, err))
+	}
+	rec := ShapeRecord{Num: n, Shape: shape}
+
+	if d.dbf != nil && n <= d.dbf.count {
+		d.smu.Lock()
+		rec.Attrs, rec.Deleted, err = d.dbf.record(n)
+		d.smu.Unlock()
+		if err != nil {
+			return ShapeRecord{}, Err(&GoFileError{
+				Op:   prependGoFilePrefix(op),
+				Path: d.dbf.name,
+				Err:  err,
+			})
+		}
+	}
+	return rec, nil
+}
+
+func (d *shapefile) Records() ShapeIterator {
+	return &shapeIter{f: d}
+}
+
+// Close closes the .shp and .dbf files.
+func (d *shapefile) Close() error {
+	d.smu.Lock()
+	var err error
+	if d.dbf != nil {
+		err = d.dbf.close()
+	}
+	d.smu.Unlock()
+
+	if e := d.basicFile.Close(); e != nil {
+		return e
+	}
+	return err
+}
+
+func (d *shapefile) formatError(op string, err error) error {
+	return Err(&GoFileError{
+		Op:   prependGoFilePrefix(op),
+		Path: d.providedName,
+		Err:  err,
+	})
+}
+
+func (it *shapeIter) Next() bool {
+	if it.err != nil || it.n >= it.f.Len() {
+		return false
+	}
+	it.n++
+	it.rec, it.err = it.f.Record(it.n)
+	return it.err == nil
+}
+
+func (it *shapeIter) Record() ShapeRecord { return it.rec }
+func (it *shapeIter) Err() error          { return it.err }
+
+// Close stops the iteration. It does not close the
+// Shapefile.
+func (it *shapeIter) Close() error {
+	it.n = it.f.Len()
+	return nil
+}
+
+// shapeReader reads the little-endian values of a
+// record. A read past the end sets err and returns 0.
+type shapeReader struct {
+	b   []byte
+	err error
+}
+
+func (r *shapeReader) next(n int) []byte {
+	if r.err != nil || len(r.b) < n {
+		r.err = fmt.Errorf("truncated: %w", ErrInvalid)
+		return make([]byte, n)
+	}
+	b := r.b[:n]
+	r.b = r.b[n:]
+	return b
+}
+
+func (r *shapeReader) int32() int { return int(int32(binary.LittleEndian.Uint32(r.next(4)))) }
+func (r *shapeReader) float64() float64 {
+	return math.Float64frombits(binary.LittleEndian.Uint64(r.next(8)))
+}
+func (r *shapeReader) box() Box { return readBox(r.next(32)) }
+
+// count reads a count of items of size bytes and checks
+// that the record is long enough to hold them.
+func (r *shapeReader) count(size int) int {
+	n := r.int32()
+	if n < 0 || n > len(r.b)/size {
+		r.err = fmt.Errorf("bad count %d: %w", n, ErrInvalid)
+		return 0
+	}
+	return n
+}
+
+func readBox(b []byte) Box {
+	f := func(i int) float64 { return math.Float64frombits(binary.LittleEndian.Uint64(b[8*i:])) }
+	return Box{f(0), f(1), f(2), f(3)}
+}
+
+// parseShape parses the content of a record.
+func parseShape(b []byte) (Shape, error) {
+	r := &shapeReader{b: b}
+	s := Shape{Type: ShapeType(r.int32())}
+
+	switch s.Type.base() {
+	case ShapeNull:
+		return s, r.err
+
+	case ShapePoint:
+		p := Point{X: r.float64(), Y: r.float64()}
+		if s.Type.hasZ() {
+			p.Z = r.float64()
+		}
+		if s.Type.hasM() && len(r.b) >= 8 {
+			p.M = r.float64()
+		}
+		s.Box = Box{p.X, p.Y, p.X, p.Y}
+		s.Parts = []int{0}
+		s.Points = []Point{p}
+		return s, r.err
+
+	case ShapeMultiPoint:
+		s.Box = r.box()
+		s.Parts = []int{0}
+		s.Points = make([]Point, r.count(16))
+
+	case ShapePolyLine, ShapePolygon, ShapeMultiPatch:
+		s.Box = r.box()
+		nparts := r.int32()
+		npoints := r.int32()
+		if nparts < 0 || npoints < 0 || nparts > len(r.b)/4 || npoints > len(r.b)/16 {
+			return s, fmt.Errorf("bad part or point count: %w", ErrInvalid)
+		}
+		s.Parts = make([]int, nparts)
+		for i := range s.Parts {
+			s.Parts[i] = r.int32()
+			if s.Parts[i] < 0 || s.Parts[i] >= npoints || i > 0 && s.Parts[i] <= s.Parts[i-1] {
+				return s, fmt.Errorf("bad part index %d: %w", s.Parts[i], ErrInvalid)
+			}
+		}
+		if s.Type == ShapeMultiPatch {
+			r.next(4 * nparts) // part types
+		}
+		s.Points = make([]Point, npoints)
+
+	default:
+		return s, fmt.Errorf("unknown shape type %d: %w", int32(s.Type), ErrInvalid)
+	}
+
+	for i := range s.Points {
+		s.Points[i].X = r.float64()
+		s.Points[i].Y = r.float64()
+	}
+	if s.Type.hasZ() {
+		r.next(16) // Z range
+		for i := range s.Points {
+			s.Points[i].Z = r.float64()
+		}
+	}
+	// M values are optional in the Z types.
+	if s.Type.hasM() && len(r.b) >= 16+8*len(s.Points) {
+		r.next(16) // M range
+		for i := range s.Points {
+			s.Points[i].M = r.float64()
+		}
+	}
+	return s, r.err
+}
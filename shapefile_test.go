@@ -0,0 +1,244 @@
+package basicfile
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// testShapefile writes a PolyLine Shapefile with two
+// records, and its index and attributes, to dir and
+// returns the path of the .shp file.
+func testShapefile(t *testing.T, dir string) string {
+	t.Helper()
+
+	le := binary.LittleEndian
+	polyline := func(parts []int32, pts ...float64) []byte {
+		var b bytes.Buffer
+		binary.Write(&b, le, int32(ShapePolyLine))
+		binary.Write(&b, le, [4]float64{pts[0], pts[1], pts[len(pts)-2], pts[len(pts)-1]})
+		binary.Write(&b, le, int32(len(parts)))
+		binary.Write(&b, le, int32(len(pts)/2))
+		binary.Write(&b, le, parts)
+		binary.Write(&b, le, pts)
+		return b.Bytes()
+	}
+	records := [][]byte{
+		polyline([]int32{0}, 0, 0, 1, 1),
+		polyline([]int32{0, 2}, 0, 0, 1, 0, 5, 5, 6, 6, 7, 7),
+	}
+
+	header := func(length int) []byte {
+		h := make([]byte, shpHeaderSize)
+		binary.BigEndian.PutUint32(h[0:], shpFileCode)
+		binary.BigEndian.PutUint32(h[24:], uint32(length/2))
+		le.PutUint32(h[28:], 1000)
+		le.PutUint32(h[32:], uint32(ShapePolyLine))
+		for i, v := range []float64{0, 0, 7, 7} {
+			le.PutUint64(h[36+8*i:], math.Float64bits(v))
+		}
+		return h
+	}
+
+	var shp, shx bytes.Buffer
+	off := shpHeaderSize
+	for i, rec := range records {
+		var rh [8]byte
+		binary.BigEndian.PutUint32(rh[0:], uint32(i+1))
+		binary.BigEndian.PutUint32(rh[4:], uint32(len(rec)/2))
+		shp.Write(rh[:])
+		shp.Write(rec)
+
+		var ih [8]byte
+		binary.BigEndian.PutUint32(ih[0:], uint32(off/2))
+		binary.BigEndian.PutUint32(ih[4:], uint32(len(rec)/2))
+		shx.Write(ih[:])
+		off += 8 + len(rec)
+	}
+
+	var dbf bytes.Buffer
+	fields := []DBFField{
+		{Name: "NAME", Type: 'C', Length: 10},
+		{Name: "LANES", Type: 'N', Length: 4},
+		{Name: "LEN", Type: 'N', Length: 8, Decimals: 2},
+		{Name: "PAVED", Type: 'L', Length: 1},
+		{Name: "BUILT", Type: 'D', Length: 8},
+	}
+	recordLen := 1
+	for _, f := range fields {
+		recordLen += f.Length
+	}
+	h := make([]byte, dbfHeaderSize)
+	h[0] = 3
+	le.PutUint32(h[4:], 2)
+	le.PutUint16(h[8:], uint16(dbfHeaderSize+dbfFieldSize*len(fields)+1))
+	le.PutUint16(h[10:], uint16(recordLen))
+	dbf.Write(h)
+	for _, f := range fields {
+		fd := make([]byte, dbfFieldSize)
+		copy(fd, f.Name)
+		fd[11], fd[16], fd[17] = f.Type, byte(f.Length), byte(f.Decimals)
+		dbf.Write(fd)
+	}
+	dbf.WriteByte(0x0D)
+	dbf.WriteString(" " + "Main St   " + "   2" + "   12.50" + "T" + "19991231")
+	dbf.WriteString("*" + "Caf\xe9      " + "    " + "        " + "?" + "        ")
+	dbf.WriteByte(0x1A)
+
+	path := filepath.Join(dir, "roads.shp")
+	for name, b := range map[string][]byte{
+		"roads.shp": append(header(off), shp.Bytes()...),
+		"roads.shx": append(header(shpHeaderSize+shx.Len()), shx.Bytes()...),
+		"roads.dbf": dbf.Bytes(),
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), b, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return path
+}
+
+func TestShapefile(t *testing.T) {
+	dir := t.TempDir()
+	testShapefile(t, dir)
+
+	// Any file of the set opens the Shapefile.
+	f, err := OpenShapefile(filepath.Join(dir, "roads.dbf"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	if f.ShapeType() != ShapePolyLine || f.Len() != 2 {
+		t.Fatalf("ShapeType, Len = %v, %d, want PolyLine, 2", f.ShapeType(), f.Len())
+	}
+	if want := (Box{0, 0, 7, 7}); f.Bounds() != want {
+		t.Errorf("Bounds() = %v, want %v", f.Bounds(), want)
+	}
+	if len(f.Fields()) != 5 || f.Fields()[2].Name != "LEN" {
+		t.Errorf("Fields() = %v", f.Fields())
+	}
+
+	r, err := f.Record(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r.Shape.Parts) != 2 || !reflect.DeepEqual(r.Shape.Part(1), []Point{{X: 5, Y: 5}, {X: 6, Y: 6}, {X: 7, Y: 7}}) {
+		t.Errorf("Record(2).Shape = %+v", r.Shape)
+	}
+	wantAttrs := map[string]interface{}{
+		"NAME": "Café", "LANES": nil, "LEN": nil, "PAVED": nil, "BUILT": nil,
+	}
+	if !reflect.DeepEqual(r.Attrs, wantAttrs) || !r.Deleted {
+		t.Errorf("Record(2) attrs = %v, deleted %v, want %v, true", r.Attrs, r.Deleted, wantAttrs)
+	}
+
+	it := f.Records()
+	defer it.Close()
+	var nums []int
+	for it.Next() {
+		nums = append(nums, it.Record().Num)
+	}
+	if it.Err() != nil || !reflect.DeepEqual(nums, []int{1, 2}) {
+		t.Errorf("Records() = %v, %v", nums, it.Err())
+	}
+
+	r, _ = f.Record(1)
+	wantAttrs = map[string]interface{}{
+		"NAME":  "Main St",
+		"LANES": int64(2),
+		"LEN":   12.5,
+		"PAVED": true,
+		"BUILT": time.Date(1999, 12, 31, 0, 0, 0, 0, time.UTC),
+	}
+	if !reflect.DeepEqual(r.Attrs, wantAttrs) || r.Deleted {
+		t.Errorf("Record(1) attrs = %v, want %v", r.Attrs, wantAttrs)
+	}
+
+	if _, err := f.Record(3); err == nil {
+		t.Error("Record(3) succeeded")
+	}
+}
+
+func TestShapefileNoIndex(t *testing.T) {
+	dir := t.TempDir()
+	path := testShapefile(t, dir)
+	os.Remove(filepath.Join(dir, "roads.shx"))
+	os.Remove(filepath.Join(dir, "roads.dbf"))
+
+	f, err := OpenShapefile(path[:len(path)-len(".shp")])
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	if f.Len() != 2 || f.Fields() != nil {
+		t.Fatalf("Len, Fields = %d, %v, want 2, nil", f.Len(), f.Fields())
+	}
+	r, err := f.Record(2)
+	if err != nil || r.Attrs != nil || len(r.Shape.Points) != 5 {
+		t.Errorf("Record(2) = %+v, %v", r, err)
+	}
+}
+
+func TestParseShapePointZ(t *testing.T) {
+	var b bytes.Buffer
+	binary.Write(&b, binary.LittleEndian, int32(ShapePointZ))
+	binary.Write(&b, binary.LittleEndian, [4]float64{1, 2, 3, 4})
+
+	s, err := parseShape(b.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []Point{{1, 2, 3, 4}}; !reflect.DeepEqual(s.Points, want) {
+		t.Errorf("Points = %v, want %v", s.Points, want)
+	}
+
+	if _, err := parseShape(b.Bytes()[:12]); err == nil {
+		t.Error("parseShape of a truncated record succeeded")
+	}
+}
+
+func TestShapefileBadRecords(t *testing.T) {
+	dir := t.TempDir()
+	path := testShapefile(t, dir)
+
+	// Give record 2 a huge length in the index.
+	shx := filepath.Join(dir, "roads.shx")
+	b, err := os.ReadFile(shx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	binary.BigEndian.PutUint32(b[shpHeaderSize+12:], math.MaxUint32)
+	if err := os.WriteFile(shx, b, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := OpenShapefile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, err := f.Record(2); !errors.Is(err, ErrInvalid) {
+		t.Errorf("Record(2) with a bad length = %v, want ErrInvalid", err)
+	}
+
+	var rec bytes.Buffer
+	binary.Write(&rec, binary.LittleEndian, int32(ShapePolyLine))
+	binary.Write(&rec, binary.LittleEndian, [4]float64{})
+	binary.Write(&rec, binary.LittleEndian, []int32{2, 3, 2, 0}) // parts, points, parts
+	binary.Write(&rec, binary.LittleEndian, make([]float64, 6))
+	if _, err := parseShape(rec.Bytes()); !errors.Is(err, ErrInvalid) {
+		t.Errorf("parseShape with decreasing parts = %v, want ErrInvalid", err)
+	}
+
+	if p := (Shape{Type: ShapeNull}).Part(0); p != nil {
+		t.Errorf("Part(0) of a null shape = %v, want nil", p)
+	}
+}
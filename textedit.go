@@ -0,0 +1,314 @@
+package basicfile
+
+import (
+	"os"
+	"regexp"
+	"strings"
+)
+
+// InsertLine inserts s as line i (1-based) of the file,
+// moving the lines from i onward down by one. If i is
+// one more than the number of lines, s is appended.
+//
+// Edits are made in memory and written by Save.
+func (d *textfile) InsertLine(i int, s string) error {
+	return d.edit("gofile.InsertLine", func(lines []string) ([]string, bool) {
+		if i < 1 || i > len(lines)+1 {
+			return nil, false
+		}
+		lines = append(lines, "")
+		copy(lines[i:], lines[i-1:])
+		lines[i-1] = s
+		return lines, true
+	})
+}
+
+// AppendLine adds s as the last line of the file.
+//
+// Edits are made in memory and written by Save.
+func (d *textfile) AppendLine(s string) error {
+	return d.edit("gofile.AppendLine", func(lines []string) ([]string, bool) {
+		return append(lines, s), true
+	})
+}
+
+// DeleteLines removes lines from through to (1-based
+// and inclusive) of the file.
+//
+// Edits are made in memory and written by Save.
+func (d *textfile) DeleteLines(from, to int) error {
+	return d.edit("gofile.DeleteLines", func(lines []string) ([]string, bool) {
+		if from < 1 || to < from || to > len(lines) {
+			return nil, false
+		}
+		return append(lines[:from-1], lines[to:]...), true
+	})
+}
+
+// ReplaceLine replaces line i (1-based) of the file
+// with s.
+//
+// Edits are made in memory and written by Save.
+func (d *textfile) ReplaceLine(i int, s string) error {
+	return d.edit("gofile.ReplaceLine", func(lines []string) ([]string, bool) {
+		if i < 1 || i > len(lines) {
+			return nil, false
+		}
+		lines[i-1] = s
+		return lines, true
+	})
+}
+
+// ReplaceAll replaces the matches of re in each line of
+// the file with repl, as regexp.ReplaceAllString does,
+// and returns the number of lines that changed. Matches
+// do not span lines.
+//
+// Edits are made in memory and written by Save.
+func (d *textfile) ReplaceAll(re *regexp.Regexp, repl string) (int, error) {
+	n := 0
+	err := d.edit("gofile.ReplaceAll", func(lines []string) ([]string, bool) {
+		if re == nil {
+			return nil, false
+		}
+		for i, line := range lines {
+			s := re.ReplaceAllString(line, repl)
+			if s != line {
+				lines[i] = s
+				n++
+			}
+		}
+		return lines, true
+	})
+	return n, err
+}
+
+// Modified reports whether there are edits that
+// have not been saved.
+func (d *textfile) Modified() bool {
+	d.tmu.Lock()
+	defer d.tmu.Unlock()
+	return d.modified
+}
+
+// Save writes the edited text to the file atomically,
+// as WriteFileAtomic does, keeping the file mode. Edited
+// lines keep their line endings, so a file with mixed
+// line endings stays mixed; new lines use the first line
+// ending of the file. The final line separator, encoding
+// and byte order mark of the original file are also
+// preserved. Save does nothing if there are no unsaved
+// edits.
+//
+// If there is an error, it will be of type *GoFileError.
+func (d *textfile) Save() error {
+	d.tmu.Lock()
+	defer d.tmu.Unlock()
+
+	if !d.modified {
+		return nil
+	}
+
+	fi, err := d.basicFile.Stat()
+	if err != nil {
+		return err
+	}
+	b, err := encode(d.data, d.enc)
+	if err != nil {
+		return Err(&GoFileError{
+			Op:   prependGoFilePrefix("gofile.Save"),
+			Path: d.providedName,
+			Err:  err,
+		})
+	}
+	err = WriteFileAtomic(d.providedName, append(d.bom.Bytes(), b...), fi.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	d.modified = false
+	d.index = nil
+
+	// The open descriptor, if any, refers to the replaced
+	// file; the new one is opened when next needed.
+	d.basicFile.Close()
+	d.basicFile.Dirty()
+	return nil
+}
+
+// edit applies fn to the lines of the file and stores
+// the result as the text of the file, as modify does.
+// Lines keep their line endings, as keepEndings finds
+// them.
+func (d *textfile) edit(op string, fn func(lines []string) ([]string, bool)) error {
+	return d.modify(op, func() bool {
+		old := splitLines(d.data, d.linesep)
+		lines, ok := fn(append([]string(nil), old...))
+		if ok {
+			ends := keepEndings(old, lineEndings(d.data, d.linesep), lines)
+			d.data = d.join(lines, ends)
+		}
+		return ok
+	})
+}
+
+// modify calls fn with d.tmu held and the text of the
+// file loaded in d.data, and marks the file modified. If
+// fn reports false, the arguments of op were invalid and
+// fn has changed nothing. A streamed file is loaded into
+// memory first.
+func (d *textfile) modify(op string, fn func() bool) error {
+	d.tmu.Lock()
+	defer d.tmu.Unlock()
+
+	if d.streaming {
+		d.streaming = false
+		d.loaded = false
+	}
+	if _, err := d.text(); err != nil {
+		return err
+	}
+
+	if !fn() {
+		return NewGoFileError(op, d.providedName, ErrInvalid)
+	}
+	d.modified = true
+	d.dirty = true
+	return nil
+}
+
+// join joins lines, ending each with ends[i], or with
+// the line ending of the original file if ends[i] is
+// empty. The last line has a line ending only if the
+// original file had one. The caller must hold d.tmu.
+func (d *textfile) join(lines, ends []string) string {
+	var b strings.Builder
+	for i, line := range lines {
+		b.WriteString(line)
+		if i == len(lines)-1 && !d.finalEOL {
+			break
+		}
+		if ends[i] != "" {
+			b.WriteString(ends[i])
+		} else {
+			b.WriteString(d.eol)
+		}
+	}
+	return b.String()
+}
+
+// lineEndings returns the line ending of each line of s,
+// as splitLines splits it. The last line is "" if it has
+// no line ending.
+func lineEndings(s string, sep byte) []string {
+	lines := strings.SplitAfter(s, string(sep))
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	ends := make([]string, len(lines))
+	for i, line := range lines {
+		switch {
+		case line[len(line)-1] != sep:
+		case sep == '\n' && strings.HasSuffix(line, "\r\n"):
+			ends[i] = "\r\n"
+		default:
+			ends[i] = string(sep)
+		}
+	}
+	return ends
+}
+
+// keepEndings returns the line endings for lines, the
+// result of editing old, whose lines end with ends. Lines
+// before and after the edit keep their endings; edited
+// lines take the endings of the old lines in the same
+// place, and added lines get "".
+func keepEndings(old, ends, lines []string) []string {
+	p := 0
+	for p < len(old) && p < len(lines) && old[p] == lines[p] {
+		p++
+	}
+	q := 0
+	for q < len(old)-p && q < len(lines)-p && old[len(old)-1-q] == lines[len(lines)-1-q] {
+		q++
+	}
+
+	kept := make([]string, len(lines))
+	for i := range kept {
+		switch {
+		case i >= len(lines)-q:
+			kept[i] = ends[i-len(lines)+len(old)]
+		case i < len(old)-q:
+			kept[i] = ends[i]
+		}
+	}
+	return kept
+}
+
+// appendText writes text to the end of the file on disk
+// in the encoding of the file, first adding a line ending
+// if the file does not end with one. If sync is true, the
+// file is synced before appendText returns. The caller
+// must hold d.tmu.
+//
+// appendText fails if there are unsaved edits.
+func (d *textfile) appendText(op, text string, sync bool) error {
+	if d.modified {
+		return NewGoFileError(op, d.providedName, ErrInvalid)
+	}
+
+	if !d.endsWithEOL() {
+		text = d.eol + text
+	}
+	b, err := encode(text, d.enc)
+	if err != nil {
+		return Err(&GoFileError{
+			Op:   prependGoFilePrefix(op),
+			Path: d.providedName,
+			Err:  err,
+		})
+	}
+
+	f, err := os.OpenFile(d.providedName, os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		return Err(NewGoFileError(op, d.providedName, err))
+	}
+	_, err = f.Write(b)
+	if err == nil && sync {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return Err(NewGoFileError(op, d.providedName, err))
+	}
+
+	d.dirty = true
+	d.data = ""
+	d.loaded = false
+	d.basicFile.Dirty()
+	return nil
+}
+
+// endsWithEOL reports whether the file on disk is empty
+// or ends with a line ending. The caller must hold d.tmu.
+func (d *textfile) endsWithEOL() bool {
+	fi, err := os.Stat(d.providedName)
+	if err != nil {
+		return true
+	}
+	unit := int64(d.enc.unit())
+	if fi.Size()-int64(len(d.bom.Bytes())) < unit {
+		return true
+	}
+
+	b := make([]byte, unit)
+	if _, err := d.basicFile.ReadAt(b, fi.Size()-unit); err != nil {
+		return true
+	}
+	c := uint16(b[0])
+	if unit == 2 {
+		c = utf16Unit(b, d.enc)
+	}
+	return c == '\n' || c == '\r'
+}
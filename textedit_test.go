@@ -0,0 +1,75 @@
+package basicfile
+
+import (
+	"os"
+	"regexp"
+	"testing"
+)
+
+func TestTextFileEdit(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want string
+	}{
+		{"lf", "a=1\nb=2\nc=3\n", "first\na=10\nc=30\nlast\n"},
+		{"crlf", "a=1\r\nb=2\r\nc=3\r\n", "first\r\na=10\r\nc=30\r\nlast\r\n"},
+		{"no final newline", "a=1\nb=2\nc=3", "first\na=10\nc=30\nlast"},
+		{"mixed", "a=1\r\nb=2\nc=3\n", "first\r\na=10\r\nc=30\nlast\r\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeTestFile(t, "edit.conf", tt.data)
+			os.Chmod(path, 0o600)
+
+			f, err := OpenText(path, WithStreaming())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if err := f.InsertLine(1, "first"); err != nil {
+				t.Fatal(err)
+			}
+			if err := f.DeleteLines(3, 3); err != nil {
+				t.Fatal(err)
+			}
+			if err := f.AppendLine("last"); err != nil {
+				t.Fatal(err)
+			}
+			n, err := f.ReplaceAll(regexp.MustCompile(`=(\d)$`), "=${1}0")
+			if err != nil || n != 2 {
+				t.Fatalf("ReplaceAll() = %d, %v, want 2 lines", n, err)
+			}
+			if err := f.ReplaceLine(9, "x"); err == nil {
+				t.Error("ReplaceLine(9) succeeded, want error")
+			}
+
+			if b, _ := os.ReadFile(path); string(b) != tt.data {
+				t.Fatalf("file changed before Save: %q", b)
+			}
+			if !f.Modified() {
+				t.Error("Modified() = false before Save")
+			}
+			if err := f.Save(); err != nil {
+				t.Fatal(err)
+			}
+			if f.Modified() {
+				t.Error("Modified() = true after Save")
+			}
+
+			b, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(b) != tt.want {
+				t.Errorf("saved %q, want %q", b, tt.want)
+			}
+			if fi, _ := os.Stat(path); fi.Mode().Perm() != 0o600 {
+				t.Errorf("mode = %v, want 0600", fi.Mode().Perm())
+			}
+			if line, err := f.ReadLine(2); err != nil || line != "a=10" {
+				t.Errorf("ReadLine(2) after Save = %q, %v, want %q", line, err, "a=10")
+			}
+		})
+	}
+}
@@ -1,67 +1,487 @@
 package basicfile
 
 import (
-	"bufio"
-	"bytes"
+	"io"
+	"os"
+	"regexp"
 	"strings"
+	"sync"
 )
 
-type TextFile interface {
-	BasicFile
-	Text() string
-	Lines() (retval []string, err error)
-	Sep(c byte)
-}
-
-// textfile is a basicfile type that is
-// specialized for utf-8 string data
-type textfile struct {
-	basicFile
-	linesep   byte `default:"\n"`
-	recordsep byte `default:"\t"`
-	wordsep   byte `default:" "`
-	data      string
-	dirty     bool
-	lines     []string // only used JIT
-	records   []string // only used JIT
-	words     []string // only used JIT
-}
-
-func (d *textfile) Data() string        { return d.data }
-func (d *textfile) Dirty() bool         { return d.dirty }
-func (d *textfile) Sep() byte           { return d.linesep }
-func (d *textfile) RecordSep() byte     { return d.recordsep }
-func (d *textfile) WordSep() byte       { return d.wordsep }
-func (d *textfile) SetSep(c byte)       { d.linesep = c }
-func (d *textfile) SetRecordSep(c byte) { d.recordsep = c }
-func (d *textfile) String() string      { return d.Data() }
+type (
+	// TextFile is a BasicFile that is specialized
+	// for string data. The text is decoded to UTF-8
+	// from the encoding of the file. It is divided
+	// into lines by the line separator, lines into
+	// records by the record separator and the text
+	// into words by the word separator.
+	TextFile interface {
+		BasicFile
+
+		// Text returns the contents of the file.
+		Text() string
+
+		// Lines returns the lines of the file,
+		// without line separators.
+		Lines() ([]string, error)
+
+		// Records returns the fields of each line,
+		// separated by the record separator. Empty
+		// fields are kept.
+		Records() ([][]string, error)
+
+		// Words returns the words of the file,
+		// separated by the word separator or line
+		// breaks. Empty words are skipped.
+		Words() ([]string, error)
+
+		// LineIter returns an iterator that streams the
+		// lines of the file from disk, with their byte
+		// offsets.
+		LineIter() LineIterator
+
+		// ScanLines calls fn for each line of the file,
+		// streaming the file from disk.
+		ScanLines(fn func(lineNo int, line []byte) error) error
+
+		// BuildLineIndex builds or updates the index of
+		// line offsets used by ReadLine and ReadLines.
+		BuildLineIndex() error
+
+		// ReadLine returns line n (1-based) of the file.
+		ReadLine(n int) (string, error)
+
+		// ReadLines returns lines from through to
+		// (1-based and inclusive) of the file.
+		ReadLines(from, to int) ([]string, error)
+
+		// InsertLine inserts s as line i (1-based).
+		InsertLine(i int, s string) error
+
+		// AppendLine adds s as the last line.
+		AppendLine(s string) error
+
+		// DeleteLines removes lines from through to
+		// (1-based and inclusive).
+		DeleteLines(from, to int) error
+
+		// ReplaceLine replaces line i (1-based) with s.
+		ReplaceLine(i int, s string) error
+
+		// ReplaceAll replaces the matches of re in each
+		// line with repl and returns the number of lines
+		// that changed.
+		ReplaceAll(re *regexp.Regexp, repl string) (int, error)
+
+		// Modified reports whether there are unsaved edits.
+		Modified() bool
+
+		// Save writes the edited text to the file
+		// atomically.
+		Save() error
+
+		// LineEnding returns the line-ending style
+		// of the file.
+		LineEnding() LineEnding
+
+		// BOM returns the byte order mark at the
+		// start of the file.
+		BOM() BOM
+
+		// NormalizeLineEndings converts every line
+		// ending to style.
+		NormalizeLineEndings(style LineEnding) error
+
+		// StripBOM removes the byte order mark.
+		StripBOM() error
+
+		// Encoding returns the character encoding
+		// of the file.
+		Encoding() Encoding
+
+		// Stats returns wc-style counts for the file.
+		Stats() (TextStats, error)
+
+		// SetEncoding sets the character encoding
+		// used by Save.
+		SetEncoding(e Encoding) error
+
+		Sep() byte
+		SetSep(c byte)
+		RecordSep() byte
+		SetRecordSep(c byte)
+		WordSep() byte
+		SetWordSep(c byte)
+	}
+
+	// A TextOption configures a TextFile.
+	TextOption func(*textfile)
+
+	// textfile is a basicfile type that is
+	// specialized for string data
+	textfile struct {
+		basicFile
+		tmu       sync.Mutex // guards the fields below
+		linesep   byte       `default:"\n"`
+		recordsep byte       `default:"\t"`
+		wordsep   byte       `default:" "`
+		streaming bool       // data is read from disk when needed
+		data      string
+		loaded    bool       // data holds the contents of the file
+		dirty     bool       // cached values must be recalculated
+		lines     []string   // only used JIT
+		records   [][]string // only used JIT
+		words     []string   // only used JIT
+		index     *lineIndex // line offsets for ReadLine
+		modified  bool       // data has unsaved edits
+		eol       string     // line ending written by Save
+		finalEOL  bool       // the text ends with a line ending
+		ending    LineEnding // line-ending style of the file
+		bom       BOM        // byte order mark, not part of data
+		enc       Encoding   // encoding of the file
+		encSet    bool       // enc was given, not detected
+	}
+)
+
+// WithLineSep sets the line separator. The default
+// is '\n'. When it is '\n', a '\r' before it is
+// also removed, as with bufio.ScanLines, and it is
+// changed to '\r' for files that only use "\r" line
+// endings.
+func WithLineSep(c byte) TextOption {
+	return func(d *textfile) { d.linesep = c }
+}
+
+// WithRecordSep sets the record (field) separator.
+// The default is '\t'.
+func WithRecordSep(c byte) TextOption {
+	return func(d *textfile) { d.recordsep = c }
+}
+
+// WithWordSep sets the word separator. The default
+// is ' '.
+func WithWordSep(c byte) TextOption {
+	return func(d *textfile) { d.wordsep = c }
+}
+
+// WithEncoding sets the character encoding of the file.
+// By default, a byte order mark selects UTF-8 or UTF-16.
+// A file without one is UTF-16 if its NUL bytes show a
+// byte order, UTF-8 if it is valid UTF-8, and otherwise
+// Windows-1252, or Latin-1 if it has bytes that
+// Windows-1252 does not define. A streamed file is
+// sniffed from its start.
+func WithEncoding(e Encoding) TextOption {
+	return func(d *textfile) {
+		d.enc = e
+		d.encSet = true
+	}
+}
+
+// WithStreaming reads the file from disk each time its
+// text is needed instead of holding it in memory. Lines
+// and Words are built a line at a time, as LineIter
+// reads them, and Text decodes the file as it is read.
+func WithStreaming() TextOption {
+	return func(d *textfile) { d.streaming = true }
+}
+
+// OpenText opens the named file as a TextFile. Unless
+// WithStreaming is given, the contents are read into
+// memory immediately.
+//
+// If there is an error, it will be of type *GoFileError.
+func OpenText(name string, opts ...TextOption) (TextFile, error) {
+	d := &textfile{}
+	d.init(name, opts...)
+	if err := d.open(); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// init sets up a textfile with the default separators,
+// configured by opts.
+func (d *textfile) init(name string, opts ...TextOption) {
+	d.linesep = '\n'
+	d.recordsep = '\t'
+	d.wordsep = ' '
+	d.dirty = true
+	d.basicFile.init(name, WithReadOnly())
+	for _, opt := range opts {
+		opt(d)
+	}
+}
+
+// open checks that the file exists and reads it into
+// memory or, if it is streamed, detects its format.
+func (d *textfile) open() error {
+	if !d.enc.valid() {
+		return NewGoFileError("gofile.OpenText", d.providedName, ErrInvalid)
+	}
+	if _, err := d.basicFile.Stat(); err != nil {
+		return err
+	}
+	if d.streaming {
+		d.tmu.Lock()
+		defer d.tmu.Unlock()
+		return d.sniff()
+	}
+	return d.load()
+}
+
+func (d *textfile) Data() string    { return d.Text() }
+func (d *textfile) Sep() byte       { return d.linesep }
+func (d *textfile) RecordSep() byte { return d.recordsep }
+func (d *textfile) WordSep() byte   { return d.wordsep }
+func (d *textfile) String() string  { return d.Data() }
+
+func (d *textfile) SetSep(c byte)       { d.setSep(&d.linesep, c) }
+func (d *textfile) SetRecordSep(c byte) { d.setSep(&d.recordsep, c) }
+func (d *textfile) SetWordSep(c byte)   { d.setSep(&d.wordsep, c) }
+
+func (d *textfile) setSep(sep *byte, c byte) {
+	d.tmu.Lock()
+	defer d.tmu.Unlock()
+	*sep = c
+	d.dirty = true
+}
+
+// Dirty marks the cached lines, records and words,
+// and the cached file information, to be recalculated.
+// The text is read again from disk when next needed.
+// Unsaved edits are discarded.
+func (d *textfile) Dirty() {
+	d.basicFile.Dirty()
+
+	d.tmu.Lock()
+	defer d.tmu.Unlock()
+	d.dirty = true
+	d.data = ""
+	d.loaded = false
+	d.modified = false
+}
+
+// Text returns the contents of the file. If the file
+// cannot be read, "" is returned and the error is
+// logged.
+func (d *textfile) Text() string {
+	d.tmu.Lock()
+	defer d.tmu.Unlock()
+
+	s, err := d.text()
+	if Err(err) != nil {
+		return ""
+	}
+	return s
+}
 
 func (d *textfile) Lines() ([]string, error) {
-	if len(d.lines) == 0 || d.dirty {
-
-		// allocate lines
-		count := strings.Count(d.data, string(d.linesep))
-		size := int(len(d.data) / count)
-		retval := make([]string, size)
-
-		// allocate scanner buffer
-		// size := len(d.data)
-		// buf := bytes.NewBuffer(make([]byte, 0, size))
-		buf := bytes.NewBufferString(d.data)
-		defer buf.Reset()
-
-		// scan ...
-		scanner := bufio.NewScanner(buf)
-		scanner.Split(bufio.ScanLines)
-		for scanner.Scan() {
-			retval = append(retval, scanner.Text())
-		}
-		err := scanner.Err()
-		if err != nil {
+	d.tmu.Lock()
+	defer d.tmu.Unlock()
+	return d.linesLocked()
+}
+
+// linesLocked is Lines. The caller must hold d.tmu.
+func (d *textfile) linesLocked() ([]string, error) {
+	d.refresh()
+	if d.lines != nil {
+		return d.lines, nil
+	}
+
+	if d.streaming {
+		lines := []string{}
+		if err := d.eachLine(func(line []byte) {
+			lines = append(lines, string(line))
+		}); err != nil {
 			return nil, err
 		}
+		d.lines = lines
+		return d.lines, nil
+	}
 
-		d.lines = append(d.lines, retval...)
+	s, err := d.text()
+	if err != nil {
+		return nil, err
 	}
+	d.lines = splitLines(s, d.linesep)
 	return d.lines, nil
 }
+
+func (d *textfile) Records() ([][]string, error) {
+	d.tmu.Lock()
+	defer d.tmu.Unlock()
+
+	lines, err := d.linesLocked()
+	if err != nil {
+		return nil, err
+	}
+	if d.records == nil {
+		sep := string(d.recordsep)
+		records := make([][]string, len(lines))
+		for i, line := range lines {
+			records[i] = strings.Split(line, sep)
+		}
+		d.records = records
+	}
+	return d.records, nil
+}
+
+func (d *textfile) Words() ([]string, error) {
+	d.tmu.Lock()
+	defer d.tmu.Unlock()
+
+	d.refresh()
+	if d.words != nil {
+		return d.words, nil
+	}
+
+	if d.streaming {
+		words := []string{}
+		if err := d.eachLine(func(line []byte) {
+			words = append(words, splitWords(string(line), d.wordsep, d.linesep)...)
+		}); err != nil {
+			return nil, err
+		}
+		d.words = words
+		return d.words, nil
+	}
+
+	s, err := d.text()
+	if err != nil {
+		return nil, err
+	}
+	d.words = splitWords(s, d.wordsep, d.linesep)
+	return d.words, nil
+}
+
+// refresh discards the cached lines, records and words
+// if they are dirty or the file is streamed. The caller
+// must hold d.tmu.
+func (d *textfile) refresh() {
+	if !d.dirty && !d.streaming {
+		return
+	}
+	d.lines, d.records, d.words = nil, nil, nil
+	d.dirty = false
+}
+
+// text returns the contents of the file, reading it
+// from disk if it is streamed or not yet loaded. The
+// caller must hold d.tmu.
+func (d *textfile) text() (string, error) {
+	if d.streaming {
+		return d.stream()
+	}
+	if !d.loaded {
+		if err := d.loadLocked(); err != nil {
+			return "", err
+		}
+	}
+	return d.data, nil
+}
+
+// load reads the contents of the file into memory.
+func (d *textfile) load() error {
+	d.tmu.Lock()
+	defer d.tmu.Unlock()
+	return d.loadLocked()
+}
+
+// loadLocked reads the contents of the file into
+// memory. The caller must hold d.tmu.
+func (d *textfile) loadLocked() error {
+	s, err := d.read()
+	if err != nil {
+		return err
+	}
+	d.data = s
+	d.loaded = true
+	d.dirty = true
+	return nil
+}
+
+// read returns the contents of the file on disk,
+// without its byte order mark and decoded to UTF-8,
+// and records the format of the file as detect
+// does. The caller must hold d.tmu.
+func (d *textfile) read() (string, error) {
+	b, err := os.ReadFile(d.providedName)
+	if err != nil {
+		return "", Err(NewGoFileError("gofile.read", d.providedName, err))
+	}
+	s, err := d.detect(b, true)
+	if err != nil {
+		return "", Err(&GoFileError{
+			Op:   prependGoFilePrefix("gofile.read"),
+			Path: d.providedName,
+			Err:  err,
+		})
+	}
+	return s, nil
+}
+
+// stream returns the contents of the streamed file,
+// without its byte order mark, decoded to UTF-8 as it
+// is read from disk. The caller must hold d.tmu.
+func (d *textfile) stream() (string, error) {
+	const op = "gofile.Text"
+	f, err := os.Open(d.providedName)
+	if err != nil {
+		return "", Err(NewGoFileError(op, d.providedName, err))
+	}
+	defer f.Close()
+
+	bom, enc := d.encodingAt(f)
+	off := int64(len(bom.Bytes()))
+	if _, err := f.Seek(off, io.SeekStart); err != nil {
+		return "", Err(NewGoFileError(op, d.providedName, err))
+	}
+
+	var sb strings.Builder
+	if fi, err := f.Stat(); err == nil && enc.unit() == 1 {
+		sb.Grow(int(fi.Size() - off))
+	}
+	if _, err := io.Copy(&sb, newDecodeReader(f, enc, off)); err != nil {
+		return "", Err(&GoFileError{
+			Op:   prependGoFilePrefix(op),
+			Path: d.providedName,
+			Err:  err,
+		})
+	}
+	return sb.String(), nil
+}
+
+// splitLines splits s into lines separated by sep. A
+// final separator does not start a new line. If sep
+// is '\n', a preceding '\r' is removed from each line.
+func splitLines(s string, sep byte) []string {
+	if s == "" {
+		return []string{}
+	}
+
+	lines := strings.Split(s, string(sep))
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	if sep == '\n' {
+		for i, line := range lines {
+			lines[i] = strings.TrimSuffix(line, "\r")
+		}
+	}
+	return lines
+}
+
+// splitWords splits s into words separated by runs
+// of wordsep or line breaks.
+func splitWords(s string, wordsep, linesep byte) []string {
+	return strings.FieldsFunc(s, func(r rune) bool {
+		switch r {
+		case rune(wordsep), rune(linesep):
+			return true
+		case '\r':
+			return linesep == '\n'
+		}
+		return false
+	})
+}
@@ -0,0 +1,115 @@
+package basicfile
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func writeTestFile(t *testing.T, name, data string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(data), NormalMode); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestOpenText(t *testing.T) {
+	tests := []struct {
+		name        string
+		data        string
+		opts        []TextOption
+		wantLines   []string
+		wantRecords [][]string
+		wantWords   []string
+	}{
+		{
+			name:        "empty",
+			data:        "",
+			wantLines:   []string{},
+			wantRecords: [][]string{},
+			wantWords:   []string{},
+		},
+		{
+			name:        "no separator",
+			data:        "one line",
+			wantLines:   []string{"one line"},
+			wantRecords: [][]string{{"one line"}},
+			wantWords:   []string{"one", "line"},
+		},
+		{
+			name:        "tsv",
+			data:        "a\tb\tc\r\nd\t\tf\n",
+			wantLines:   []string{"a\tb\tc", "d\t\tf"},
+			wantRecords: [][]string{{"a", "b", "c"}, {"d", "", "f"}},
+			wantWords:   []string{"a\tb\tc", "d\t\tf"},
+		},
+		{
+			name:        "custom separators",
+			data:        "x,y;z  w;",
+			opts:        []TextOption{WithLineSep(';'), WithRecordSep(','), WithWordSep(' ')},
+			wantLines:   []string{"x,y", "z  w"},
+			wantRecords: [][]string{{"x", "y"}, {"z  w"}},
+			wantWords:   []string{"x,y", "z", "w"},
+		},
+	}
+	for _, tt := range tests {
+		for _, streaming := range []bool{false, true} {
+			opts := tt.opts
+			if streaming {
+				opts = append(opts, WithStreaming())
+			}
+			t.Run(tt.name, func(t *testing.T) {
+				f, err := OpenText(writeTestFile(t, "text.txt", tt.data), opts...)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got := f.Text(); got != tt.data {
+					t.Errorf("Text() = %q, want %q", got, tt.data)
+				}
+				if got, _ := f.Lines(); !reflect.DeepEqual(got, tt.wantLines) {
+					t.Errorf("Lines() = %q, want %q", got, tt.wantLines)
+				}
+				if got, _ := f.Records(); !reflect.DeepEqual(got, tt.wantRecords) {
+					t.Errorf("Records() = %q, want %q", got, tt.wantRecords)
+				}
+				if got, _ := f.Words(); !reflect.DeepEqual(got, tt.wantWords) {
+					t.Errorf("Words() = %q, want %q", got, tt.wantWords)
+				}
+			})
+		}
+	}
+}
+
+func TestOpenText_missing(t *testing.T) {
+	if _, err := OpenText(filepath.Join(t.TempDir(), "missing.txt")); err == nil {
+		t.Error("OpenText() on missing file succeeded, want error")
+	}
+}
+
+func TestOpenText_streamingReread(t *testing.T) {
+	path := writeTestFile(t, "stream.txt", "a b\r\nc\r\n")
+	f, err := OpenText(path, WithStreaming())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := f.Records(); !reflect.DeepEqual(got, [][]string{{"a b"}, {"c"}}) {
+		t.Errorf("Records() = %q", got)
+	}
+
+	// A streamed file is read again on every call.
+	if err := os.WriteFile(path, []byte("d\r\ne f\r\n"), NormalMode); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := f.Lines(); !reflect.DeepEqual(got, []string{"d", "e f"}) {
+		t.Errorf("Lines() after rewrite = %q", got)
+	}
+	if got, _ := f.Words(); !reflect.DeepEqual(got, []string{"d", "e", "f"}) {
+		t.Errorf("Words() after rewrite = %q", got)
+	}
+	if got := f.Text(); got != "d\r\ne f\r\n" {
+		t.Errorf("Text() after rewrite = %q", got)
+	}
+}
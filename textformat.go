@@ -0,0 +1,411 @@
+package basicfile
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"strings"
+)
+
+// LineEnding is the line-ending style of a text file.
+type LineEnding int
+
+const (
+	// NoLineEnding is reported for text with no line
+	// endings.
+	NoLineEnding LineEnding = iota
+
+	// LineEndingLF is "\n", used by Unix-like systems.
+	LineEndingLF
+
+	// LineEndingCRLF is "\r\n", used by Windows.
+	LineEndingCRLF
+
+	// LineEndingCR is "\r", used by classic Mac OS.
+	LineEndingCR
+
+	// MixedLineEndings is reported for text with more
+	// than one line-ending style.
+	MixedLineEndings
+)
+
+// String returns the line ending itself for LF, CRLF
+// and CR, and "" otherwise.
+func (e LineEnding) String() string {
+	switch e {
+	case LineEndingLF:
+		return "\n"
+	case LineEndingCRLF:
+		return "\r\n"
+	case LineEndingCR:
+		return "\r"
+	}
+	return ""
+}
+
+// BOM is the byte order mark at the start of a text file.
+type BOM int
+
+// Byte order marks recognized by TextFile.
+const (
+	NoBOM BOM = iota
+	BOMUTF8
+	BOMUTF16LE
+	BOMUTF16BE
+)
+
+var bomBytes = [...][]byte{
+	NoBOM:      nil,
+	BOMUTF8:    {0xEF, 0xBB, 0xBF},
+	BOMUTF16LE: {0xFF, 0xFE},
+	BOMUTF16BE: {0xFE, 0xFF},
+}
+
+// Bytes returns the encoded byte order mark.
+func (b BOM) Bytes() []byte {
+	if b < 0 || int(b) >= len(bomBytes) {
+		return nil
+	}
+	return append([]byte(nil), bomBytes[b]...)
+}
+
+func (b BOM) String() string {
+	switch b {
+	case BOMUTF8:
+		return "UTF-8"
+	case BOMUTF16LE:
+		return "UTF-16LE"
+	case BOMUTF16BE:
+		return "UTF-16BE"
+	}
+	return "none"
+}
+
+// detectBOM returns the byte order mark at the start of b.
+func detectBOM(b []byte) BOM {
+	for _, bom := range []BOM{BOMUTF8, BOMUTF16LE, BOMUTF16BE} {
+		if bytes.HasPrefix(b, bom.Bytes()) {
+			return bom
+		}
+	}
+	return NoBOM
+}
+
+// endingScanner detects the line-ending style of text
+// written to it in any number of pieces.
+type endingScanner struct {
+	first  LineEnding // first line ending seen
+	seen   [MixedLineEndings]bool
+	prevCR bool // the last byte written was '\r'
+}
+
+func (s *endingScanner) Write(b []byte) (int, error) {
+	for _, c := range b {
+		switch {
+		case c == '\n' && s.prevCR:
+			s.see(LineEndingCRLF)
+		case c == '\n':
+			s.see(LineEndingLF)
+		case s.prevCR:
+			s.see(LineEndingCR)
+		}
+		s.prevCR = c == '\r'
+	}
+	return len(b), nil
+}
+
+func (s *endingScanner) see(e LineEnding) {
+	if s.first == NoLineEnding {
+		s.first = e
+	}
+	s.seen[e] = true
+}
+
+// result returns the line-ending style of the text. If
+// atEOF is false, a final '\r' may yet be part of "\r\n"
+// and is not counted.
+func (s *endingScanner) result(atEOF bool) LineEnding {
+	if atEOF && s.prevCR {
+		s.see(LineEndingCR)
+		s.prevCR = false
+	}
+
+	e := NoLineEnding
+	for i, ok := range s.seen {
+		if !ok {
+			continue
+		}
+		if e != NoLineEnding {
+			return MixedLineEndings
+		}
+		e = LineEnding(i)
+	}
+	return e
+}
+
+// LineEnding returns the line-ending style of the file.
+// For a streamed file, the file is scanned from disk.
+//
+// If the file cannot be read, NoLineEnding is returned
+// and the error is logged.
+func (d *textfile) LineEnding() LineEnding {
+	d.tmu.Lock()
+	defer d.tmu.Unlock()
+
+	if !d.streaming {
+		if _, err := d.text(); Err(err) != nil {
+			return NoLineEnding
+		}
+		return d.ending
+	}
+
+	f, err := os.Open(d.providedName)
+	if Err(err) != nil {
+		return NoLineEnding
+	}
+	defer f.Close()
+
+	bom, enc := d.encodingAt(f)
+	if _, err := f.Seek(int64(len(bom.Bytes())), io.SeekStart); Err(err) != nil {
+		return NoLineEnding
+	}
+
+	var s endingScanner
+	var w io.Writer = &s
+	if enc.unit() == 2 {
+		w = &asciiUnits{w: w, enc: enc}
+	}
+	if _, err := io.Copy(w, f); Err(err) != nil {
+		return NoLineEnding
+	}
+	return s.result(true)
+}
+
+// asciiUnits writes the UTF-16 code units written to it
+// to w, one byte per unit. Units outside ASCII are
+// written as 0xFF.
+type asciiUnits struct {
+	w       io.Writer
+	enc     Encoding
+	pending []byte // first byte of a split unit
+}
+
+func (a *asciiUnits) Write(b []byte) (int, error) {
+	n := len(b)
+	if len(a.pending) > 0 && len(b) > 0 {
+		b = append(a.pending, b...)
+		a.pending = a.pending[:0]
+	}
+
+	buf := make([]byte, 0, len(b)/2)
+	for i := 0; i+1 < len(b); i += 2 {
+		u := utf16Unit(b[i:], a.enc)
+		if u >= 0x80 {
+			u = 0xFF
+		}
+		buf = append(buf, byte(u))
+	}
+	if len(b)%2 == 1 {
+		a.pending = append(a.pending, b[len(b)-1])
+	}
+
+	if _, err := a.w.Write(buf); err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+// BOM returns the byte order mark at the start of the
+// file. The mark is not part of the text returned by
+// Text, Lines or LineIter, but it is written by Save.
+//
+// If the file cannot be read, NoBOM is returned and the
+// error is logged.
+func (d *textfile) BOM() BOM {
+	d.tmu.Lock()
+	defer d.tmu.Unlock()
+
+	if !d.streaming {
+		if _, err := d.text(); Err(err) != nil {
+			return NoBOM
+		}
+	}
+	return d.bom
+}
+
+// Encoding returns the character encoding of the file.
+//
+// If the file cannot be read, EncodingUTF8 is returned
+// and the error is logged.
+func (d *textfile) Encoding() Encoding {
+	d.tmu.Lock()
+	defer d.tmu.Unlock()
+
+	if !d.streaming {
+		if _, err := d.text(); Err(err) != nil {
+			return EncodingUTF8
+		}
+	}
+	return d.enc
+}
+
+// SetEncoding sets the character encoding that Save
+// writes the file in. A file saved as UTF-16 always
+// has a byte order mark; otherwise a byte order mark
+// is kept, in the new encoding, if the file had one
+// and the encoding has one.
+//
+// Edits are made in memory and written by Save.
+func (d *textfile) SetEncoding(e Encoding) error {
+	return d.modify("gofile.SetEncoding", func() bool {
+		if !e.valid() {
+			return false
+		}
+		d.enc = e
+		d.encSet = true
+		if d.bom != NoBOM || e.unit() == 2 {
+			d.bom = e.bom()
+		}
+		return true
+	})
+}
+
+// NormalizeLineEndings converts every line ending in the
+// file to style, which must be LineEndingLF,
+// LineEndingCRLF or LineEndingCR. If the line separator
+// is '\n' or '\r', it is changed to match style.
+//
+// Edits are made in memory and written by Save.
+func (d *textfile) NormalizeLineEndings(style LineEnding) error {
+	return d.modify("gofile.NormalizeLineEndings", func() bool {
+		eol := style.String()
+		if eol == "" {
+			return false
+		}
+
+		s := strings.ReplaceAll(d.data, "\r\n", "\n")
+		s = strings.ReplaceAll(s, "\r", "\n")
+		if style != LineEndingLF {
+			s = strings.ReplaceAll(s, "\n", eol)
+		}
+
+		d.data = s
+		d.eol = eol
+		if d.ending != NoLineEnding {
+			d.ending = style
+		}
+		if d.linesep == '\n' || d.linesep == '\r' {
+			d.linesep = eol[len(eol)-1]
+		}
+		return true
+	})
+}
+
+// StripBOM removes the byte order mark, if any, from the
+// file.
+//
+// Edits are made in memory and written by Save.
+func (d *textfile) StripBOM() error {
+	return d.modify("gofile.StripBOM", func() bool {
+		d.bom = NoBOM
+		return true
+	})
+}
+
+// detect records the byte order mark, encoding,
+// line-ending style and final line ending of b, the
+// contents of the file, and returns the text that
+// follows the byte order mark, decoded to UTF-8. If
+// atEOF is false, b is only the start of the file.
+//
+// If the line separator is '\n' and the file only uses
+// "\r" line endings, the separator is changed to '\r'.
+// The caller must hold d.tmu.
+//
+// If b is not valid in its encoding, the error is an
+// *EncodingError with the offset in the file of the
+// bad data.
+func (d *textfile) detect(b []byte, atEOF bool) (string, error) {
+	bom, enc := d.encodingFor(b, atEOF)
+	n := len(bom.Bytes())
+	text, err := decode(b[n:], enc, atEOF)
+	if err != nil {
+		if e, ok := err.(*EncodingError); ok {
+			e.Offset += int64(n)
+		}
+		return "", err
+	}
+	d.bom, d.enc = bom, enc
+
+	var s endingScanner
+	s.Write([]byte(text))
+	d.ending = s.result(atEOF)
+
+	if d.linesep == '\n' && d.ending == LineEndingCR {
+		d.linesep = '\r'
+	}
+
+	d.eol = string(d.linesep)
+	if d.linesep == '\n' && s.first == LineEndingCRLF {
+		d.eol = "\r\n"
+	}
+	d.finalEOL = text == "" || text[len(text)-1] == d.linesep
+	return text, nil
+}
+
+// encodingFor returns the byte order mark and encoding
+// of a file that starts with head; if atEOF is true,
+// head is the whole file. An encoding given with
+// WithEncoding or SetEncoding is used if there is one,
+// and only its own byte order mark is recognized.
+// Otherwise a byte order mark selects its encoding, and
+// a file without one is sniffed by sniffEncoding. The
+// caller must hold d.tmu.
+func (d *textfile) encodingFor(head []byte, atEOF bool) (BOM, Encoding) {
+	bom := detectBOM(head)
+	if !d.encSet {
+		if bom == NoBOM {
+			return bom, sniffEncoding(head, atEOF)
+		}
+		return bom, encodingOf(bom)
+	}
+	if bom != d.enc.bom() {
+		bom = NoBOM
+	}
+	return bom, d.enc
+}
+
+// encodingAt is encodingFor for the file read by r,
+// sniffed from its first defaultBufSize bytes. The
+// caller must hold d.tmu.
+func (d *textfile) encodingAt(r io.ReaderAt) (BOM, Encoding) {
+	head := make([]byte, defaultBufSize)
+	n, err := r.ReadAt(head, 0)
+	return d.encodingFor(head[:n], err == io.EOF)
+}
+
+// sniff detects the byte order mark and line-ending style
+// of a streamed file from its first bytes, so that
+// LineIter uses the same separator as Lines. The caller
+// must hold d.tmu.
+func (d *textfile) sniff() error {
+	f, err := os.Open(d.providedName)
+	if err != nil {
+		return Err(NewGoFileError("gofile.sniff", d.providedName, err))
+	}
+	defer f.Close()
+
+	b := make([]byte, defaultBufSize)
+	n, err := io.ReadFull(f, b)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return Err(NewGoFileError("gofile.sniff", d.providedName, err))
+	}
+	if _, err := d.detect(b[:n], n < len(b)); err != nil {
+		return Err(&GoFileError{
+			Op:   prependGoFilePrefix("gofile.sniff"),
+			Path: d.providedName,
+			Err:  err,
+		})
+	}
+	return nil
+}
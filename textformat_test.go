@@ -0,0 +1,103 @@
+package basicfile
+
+import (
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestTextFileFormat(t *testing.T) {
+	tests := []struct {
+		name      string
+		data      string
+		ending    LineEnding
+		bom       BOM
+		wantLines []string
+	}{
+		{"empty", "", NoLineEnding, NoBOM, []string{}},
+		{"no ending", "abc", NoLineEnding, NoBOM, []string{"abc"}},
+		{"lf", "a\nb\n", LineEndingLF, NoBOM, []string{"a", "b"}},
+		{"crlf bom", "\xEF\xBB\xBFa\r\nb\r\n", LineEndingCRLF, BOMUTF8, []string{"a", "b"}},
+		{"cr", "a\rb\r", LineEndingCR, NoBOM, []string{"a", "b"}},
+		{"mixed", "a\r\nb\nc", MixedLineEndings, NoBOM, []string{"a", "b", "c"}},
+		{"utf-16le bom", "\xFF\xFEa\x00\r\x00\n\x00b\x00", LineEndingCRLF, BOMUTF16LE, []string{"a", "b"}},
+		{"utf-16be bom", "\xFE\xFF\x00a\x00\n", LineEndingLF, BOMUTF16BE, []string{"a"}},
+	}
+	for _, tt := range tests {
+		for _, streaming := range []bool{false, true} {
+			var opts []TextOption
+			if streaming {
+				opts = append(opts, WithStreaming())
+			}
+			t.Run(tt.name, func(t *testing.T) {
+				f, err := OpenText(writeTestFile(t, "format.txt", tt.data), opts...)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got := f.LineEnding(); got != tt.ending {
+					t.Errorf("LineEnding() = %d, want %d", got, tt.ending)
+				}
+				if got := f.BOM(); got != tt.bom {
+					t.Errorf("BOM() = %v, want %v", got, tt.bom)
+				}
+				if got, _ := f.Lines(); !reflect.DeepEqual(got, tt.wantLines) {
+					t.Errorf("Lines() = %q, want %q", got, tt.wantLines)
+				}
+
+				var iter []string
+				f.ScanLines(func(_ int, line []byte) error {
+					iter = append(iter, string(line))
+					return nil
+				})
+				if len(tt.wantLines) > 0 && !reflect.DeepEqual(iter, tt.wantLines) {
+					t.Errorf("ScanLines() = %q, want %q", iter, tt.wantLines)
+				}
+				if len(tt.wantLines) > 0 {
+					if got, err := f.ReadLine(1); err != nil || got != tt.wantLines[0] {
+						t.Errorf("ReadLine(1) = %q, %v, want %q", got, err, tt.wantLines[0])
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestNormalizeLineEndings(t *testing.T) {
+	path := writeTestFile(t, "normalize.txt", "\xEF\xBB\xBFa\r\nb\rc\nd")
+
+	f, err := OpenText(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := f.NormalizeLineEndings(MixedLineEndings); err == nil {
+		t.Error("NormalizeLineEndings(MixedLineEndings) succeeded, want error")
+	}
+	if err := f.NormalizeLineEndings(LineEndingCRLF); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Save(); err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := os.ReadFile(path); string(b) != "\xEF\xBB\xBFa\r\nb\r\nc\r\nd" {
+		t.Errorf("after NormalizeLineEndings(CRLF) file = %q", b)
+	}
+
+	if err := f.StripBOM(); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.NormalizeLineEndings(LineEndingCR); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.AppendLine("e"); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Save(); err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := os.ReadFile(path); string(b) != "a\rb\rc\rd\re" {
+		t.Errorf("after NormalizeLineEndings(CR) file = %q", b)
+	}
+	if got := f.LineEnding(); got != LineEndingCR {
+		t.Errorf("LineEnding() = %d, want %d", got, LineEndingCR)
+	}
+}
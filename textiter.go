@@ -0,0 +1,255 @@
+package basicfile
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"os"
+)
+
+type (
+	// LineIterator streams the lines of a TextFile from
+	// disk without holding the file in memory. Lines may
+	// be of any length.
+	//
+	//  it := f.LineIter()
+	//  defer it.Close()
+	//  for it.Next() {
+	//  	fmt.Println(it.LineNo(), it.Offset(), string(it.Line()))
+	//  }
+	//  if err := it.Err(); err != nil { ... }
+	LineIterator interface {
+		io.Closer
+
+		// Next advances to the next line. It returns false
+		// at the end of the file or on error.
+		Next() bool
+
+		// Line returns the current line without its line
+		// separator. The slice is only valid until the
+		// next call to Next.
+		Line() []byte
+
+		// LineNo returns the 1-based number of the
+		// current line.
+		LineNo() int
+
+		// Offset returns the byte offset of the start of
+		// the current line, suitable for Seek or ReadAt.
+		Offset() int64
+
+		// Err returns the first error other than io.EOF
+		// encountered by Next.
+		Err() error
+	}
+
+	lineIter struct {
+		f      *os.File
+		name   string
+		r      *bufio.Reader
+		sep    byte
+		enc    Encoding
+		buf    []byte // holds lines longer than the reader buffer
+		line   []byte
+		lineNo int
+		off    int64 // offset of the current line
+		next   int64 // offset of the next line
+		err    error
+		done   bool
+	}
+)
+
+// LineIter returns a LineIterator over the lines of the
+// file, separated by the configured line separator as
+// in Lines and decoded to UTF-8 from the encoding of the
+// file, and skips any byte order mark. The file is
+// read from disk with its own file descriptor, so it
+// does not disturb other readers.
+//
+// If the file cannot be opened, the error is returned
+// by Err and Next returns false.
+func (d *textfile) LineIter() LineIterator {
+	d.tmu.Lock()
+	defer d.tmu.Unlock()
+	return d.lineIter()
+}
+
+// lineIter is LineIter. The caller must hold d.tmu.
+func (d *textfile) lineIter() *lineIter {
+	it := &lineIter{name: d.providedName, sep: d.linesep}
+	f, err := os.Open(d.providedName)
+	if err != nil {
+		it.err = Err(NewGoFileError("gofile.LineIter", d.providedName, err))
+		it.done = true
+		return it
+	}
+	it.f = f
+	it.r = bufio.NewReaderSize(f, defaultBufSize)
+
+	// Skip the byte order mark, as Lines does.
+	bom, enc := d.encodingAt(f)
+	if n := len(bom.Bytes()); n > 0 {
+		it.r.Discard(n)
+		it.next = int64(n)
+	}
+	it.enc = enc
+	return it
+}
+
+// ScanLines calls fn for each line of the file, in order,
+// streaming the file from disk as LineIter does. The
+// line slice is only valid during the call to fn.
+//
+// Scanning stops at the first error returned by fn,
+// which ScanLines returns.
+func (d *textfile) ScanLines(fn func(lineNo int, line []byte) error) error {
+	it := d.LineIter()
+	defer it.Close()
+
+	for it.Next() {
+		if err := fn(it.LineNo(), it.Line()); err != nil {
+			return err
+		}
+	}
+	return it.Err()
+}
+
+// eachLine calls fn for each line of the file, streamed
+// from disk as LineIter does. The line slice is only
+// valid during the call to fn. The caller must hold
+// d.tmu.
+func (d *textfile) eachLine(fn func(line []byte)) error {
+	it := d.lineIter()
+	defer it.Close()
+
+	for it.Next() {
+		fn(it.Line())
+	}
+	return it.Err()
+}
+
+func (it *lineIter) Next() bool {
+	if it.done {
+		return false
+	}
+
+	it.off = it.next
+	raw, n, err := it.read()
+	it.next += n
+	if err != nil {
+		it.done = true
+		if err != io.EOF {
+			it.err = Err(NewGoFileError("gofile.LineIter", it.name, err))
+			return false
+		}
+		if n == 0 {
+			return false
+		}
+	}
+
+	line, err := it.decode(raw)
+	if err != nil {
+		it.done = true
+		it.err = Err(&GoFileError{
+			Op:   prependGoFilePrefix("gofile.LineIter"),
+			Path: it.name,
+			Err:  err,
+		})
+		return false
+	}
+	it.line = line
+	it.lineNo++
+	return true
+}
+
+// read returns the next line of the file, without its
+// separator, and the number of bytes consumed. At the
+// end of the file, the error is io.EOF.
+func (it *lineIter) read() (line []byte, n int64, err error) {
+	if it.enc.unit() == 2 {
+		return it.readUnits()
+	}
+
+	it.buf = it.buf[:0]
+	for {
+		b, err := it.r.ReadSlice(it.sep)
+		n += int64(len(b))
+
+		if err == bufio.ErrBufferFull {
+			it.buf = append(it.buf, b...)
+			continue
+		}
+		if len(it.buf) > 0 {
+			it.buf = append(it.buf, b...)
+			b = it.buf
+		}
+		if err != nil {
+			return b, n, err
+		}
+		return b[:len(b)-1], n, nil
+	}
+}
+
+// readUnits is read for UTF-16 files, which are read
+// one code unit at a time. A final odd byte is returned
+// as part of the line.
+func (it *lineIter) readUnits() (line []byte, n int64, err error) {
+	it.buf = it.buf[:0]
+	var unit [2]byte
+	for {
+		c, err := io.ReadFull(it.r, unit[:])
+		n += int64(c)
+		if err == io.ErrUnexpectedEOF {
+			it.buf = append(it.buf, unit[0])
+			err = io.EOF
+		}
+		if err != nil {
+			return it.buf, n, err
+		}
+		if utf16Unit(unit[:], it.enc) == uint16(it.sep) {
+			return it.buf, n, nil
+		}
+		it.buf = append(it.buf, unit[:]...)
+	}
+}
+
+// decode returns raw, a line as read from the file,
+// as UTF-8 without a trailing '\r' if the separator is
+// '\n'.
+func (it *lineIter) decode(raw []byte) ([]byte, error) {
+	line := raw
+	if it.enc == EncodingUTF8 {
+		if n, ok := validUTF8(raw, true); !ok {
+			return nil, &EncodingError{Encoding: it.enc, Offset: it.off + int64(n)}
+		}
+	} else {
+		s, err := decode(raw, it.enc, true)
+		if err != nil {
+			err.(*EncodingError).Offset += it.off
+			return nil, err
+		}
+		line = []byte(s)
+	}
+
+	if it.sep == '\n' {
+		line = bytes.TrimSuffix(line, []byte{'\r'})
+	}
+	return line, nil
+}
+
+func (it *lineIter) Line() []byte  { return it.line }
+func (it *lineIter) LineNo() int   { return it.lineNo }
+func (it *lineIter) Offset() int64 { return it.off }
+func (it *lineIter) Err() error    { return it.err }
+
+// Close closes the file. It is safe to call more
+// than once.
+func (it *lineIter) Close() error {
+	it.done = true
+	if it.f == nil {
+		return nil
+	}
+	f := it.f
+	it.f = nil
+	return f.Close()
+}
@@ -0,0 +1,76 @@
+package basicfile
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLineIter(t *testing.T) {
+	long := strings.Repeat("x", 3*defaultBufSize+17)
+	data := "first\r\n" + long + "\n\nlast"
+
+	f, err := OpenText(writeTestFile(t, "iter.txt", data), WithStreaming())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"first", long, "", "last"}
+	wantOff := []int64{0, 7, int64(8 + len(long)), int64(9 + len(long))}
+
+	it := f.LineIter()
+	defer it.Close()
+
+	var got []string
+	for it.Next() {
+		i := len(got)
+		if it.LineNo() != i+1 {
+			t.Errorf("LineNo() = %d, want %d", it.LineNo(), i+1)
+		}
+		if i < len(wantOff) && it.Offset() != wantOff[i] {
+			t.Errorf("line %d: Offset() = %d, want %d", i+1, it.Offset(), wantOff[i])
+		}
+		got = append(got, string(it.Line()))
+	}
+	if err := it.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("LineIter lines differ: got %d lines, want %d", len(got), len(want))
+	}
+
+	lines, _ := f.Lines()
+	if !reflect.DeepEqual(lines, want) {
+		t.Error("LineIter and Lines disagree")
+	}
+}
+
+func TestScanLines(t *testing.T) {
+	f, err := OpenText(writeTestFile(t, "scan.txt", "a;b;c;"), WithLineSep(';'))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	err = f.ScanLines(func(lineNo int, line []byte) error {
+		got = append(got, string(line))
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"a", "b", "c"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ScanLines() = %q, want %q", got, want)
+	}
+
+	stop := errors.New("stop")
+	n := 0
+	err = f.ScanLines(func(lineNo int, line []byte) error {
+		n = lineNo
+		return stop
+	})
+	if err != stop || n != 1 {
+		t.Errorf("ScanLines() = %v after %d lines, want stop after 1", err, n)
+	}
+}
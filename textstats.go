@@ -0,0 +1,75 @@
+package basicfile
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// TextStats holds wc-style counts for a TextFile. Line
+// lengths are measured in runes, not counting the line
+// separator.
+type TextStats struct {
+	Bytes      int64 // size of the file
+	Runes      int64 // runes in all lines
+	Lines      int
+	Words      int // words separated by the word separator
+	BlankLines int // lines that are empty or only white space
+
+	MaxLineLength int // length of the longest line
+	LongestLine   int // number (1-based) of the first longest line
+
+	// The distribution of line lengths.
+	MeanLineLength     float64
+	LineLengthVariance float64
+	LineLengthStdDev   float64
+}
+
+// Stats returns counts of the bytes, runes, lines and
+// words of the file and the distribution of its line
+// lengths. The file is streamed from disk as ScanLines
+// does, so Stats may be used with files of any size.
+// Unsaved edits are not counted.
+//
+// If there is an error, it will be of type *GoFileError.
+func (d *textfile) Stats() (TextStats, error) {
+	var st TextStats
+	var lengths avgVar
+
+	d.tmu.Lock()
+	wordsep, linesep := d.wordsep, d.linesep
+	d.tmu.Unlock()
+
+	err := d.ScanLines(func(lineNo int, line []byte) error {
+		n := utf8.RuneCount(line)
+		lengths.Add(float64(n))
+
+		st.Runes += int64(n)
+		st.Lines = lineNo
+		st.Words += len(splitWords(string(line), wordsep, linesep))
+		if strings.TrimSpace(string(line)) == "" {
+			st.BlankLines++
+		}
+		if n > st.MaxLineLength || st.LongestLine == 0 {
+			st.MaxLineLength = n
+			st.LongestLine = lineNo
+		}
+		return nil
+	})
+	if err != nil {
+		return TextStats{}, err
+	}
+
+	d.basicFile.Dirty()
+	fi, err := d.basicFile.Stat()
+	if err != nil {
+		return TextStats{}, err
+	}
+	st.Bytes = fi.Size()
+
+	if lengths.GetCount() > 0 {
+		st.MeanLineLength = lengths.GetAvg()
+		st.LineLengthVariance = lengths.GetVar()
+		st.LineLengthStdDev = lengths.GetStdDev()
+	}
+	return st, nil
+}
@@ -0,0 +1,61 @@
+package basicfile
+
+import (
+	"math"
+	"testing"
+)
+
+func TestTextFileStats(t *testing.T) {
+	data := "one two\r\n\r\n  \r\nthree  four five\r\nsix"
+	for _, streaming := range []bool{false, true} {
+		var opts []TextOption
+		if streaming {
+			opts = append(opts, WithStreaming())
+		}
+		f, err := OpenText(writeTestFile(t, "stats.txt", data), opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		got, err := f.Stats()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// Line lengths are 7, 0, 2, 16 and 3.
+		want := TextStats{
+			Bytes:              int64(len(data)),
+			Runes:              28,
+			Lines:              5,
+			Words:              6,
+			BlankLines:         2,
+			MaxLineLength:      16,
+			LongestLine:        4,
+			MeanLineLength:     5.6,
+			LineLengthVariance: 32.24,
+		}
+		want.LineLengthStdDev = math.Sqrt(want.LineLengthVariance)
+
+		approx := func(a, b float64) bool { return math.Abs(a-b) < 1e-9 }
+		if !approx(got.MeanLineLength, want.MeanLineLength) ||
+			!approx(got.LineLengthVariance, want.LineLengthVariance) ||
+			!approx(got.LineLengthStdDev, want.LineLengthStdDev) {
+			t.Errorf("Stats() distribution = %v, want %v", got, want)
+		}
+		got.MeanLineLength, got.LineLengthVariance, got.LineLengthStdDev = want.MeanLineLength, want.LineLengthVariance, want.LineLengthStdDev
+		if got != want {
+			t.Errorf("Stats() = %+v, want %+v", got, want)
+		}
+	}
+}
+
+func TestTextFileStats_empty(t *testing.T) {
+	f, err := OpenText(writeTestFile(t, "empty.txt", ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := f.Stats()
+	if err != nil || got != (TextStats{}) {
+		t.Errorf("Stats() = %+v, %v, want zero", got, err)
+	}
+}
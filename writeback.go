@@ -0,0 +1,687 @@
+package basicfile
+
+import (
+	"io"
+	"io/fs"
+	"sort"
+	"sync"
+	"time"
+)
+
+// defaultMaxDirty is the default number of dirty bytes
+// held by a write-back file before they are flushed.
+const defaultMaxDirty = 1 << 22 // 4 MiB
+
+type (
+	// WriteBackOptions configures a write-back file.
+	WriteBackOptions struct {
+		// MaxDirty is the number of dirty bytes that
+		// may be held in memory before they are
+		// written to the file.
+		MaxDirty int64
+
+		// Interval, if > 0, is the period at which
+		// dirty regions are written to the file in
+		// the background.
+		Interval time.Duration
+	}
+
+	// FlushStats reports the activity of a write-back
+	// file. It may be used to tune WriteBackOptions.
+	FlushStats struct {
+		Writes       int64         // WriteAt calls
+		Coalesced    int64         // writes merged into an existing dirty region
+		Flushes      int64         // flushes that wrote at least one region
+		ByThreshold  int64         // flushes triggered by MaxDirty
+		ByTimer      int64         // flushes triggered by Interval
+		Regions      int64         // regions written to the file
+		Bytes        int64         // bytes written to the file
+		LastFlush    time.Time     // completion time of the last flush
+		LastDuration time.Duration // duration of the last flush
+	}
+
+	// WriteBackFile is a BasicFile that collects writes
+	// in memory and writes them to the file later.
+	//
+	// Overlapping and adjacent writes are coalesced into
+	// a single dirty region. Dirty regions are written,
+	// in file order, when Flush or Sync is called, when
+	// more than MaxDirty bytes are dirty, or periodically
+	// if an Interval is set. Reads always observe the
+	// most recent writes, and Stat, Reader, Writer and
+	// Handle go through the dirty regions too.
+	WriteBackFile interface {
+		BasicFile
+		io.Writer
+		io.ReaderAt
+		io.WriterAt
+		io.Seeker
+		io.WriterTo
+		io.ReaderFrom
+
+		// Truncate changes the size of the file,
+		// discarding dirty data beyond it.
+		Truncate(size int64) error
+
+		// Flush writes all dirty regions, commits the
+		// file to stable storage and closes the
+		// underlying file until it is next needed.
+		Flush() error
+
+		// Sync writes all dirty regions and commits
+		// the file to stable storage. Every write
+		// that returned before Sync was called is
+		// on disk when Sync returns.
+		Sync() error
+
+		// Stats returns a snapshot of the flush
+		// statistics.
+		Stats() FlushStats
+	}
+
+	writeBackFile struct {
+		basicFile
+		maxDirty int64
+
+		wmu     sync.Mutex // guards the fields below
+		regions []region   // dirty regions sorted by offset; never overlapping or adjacent
+		dirty   int64      // total bytes in regions
+		off     int64      // offset for Read, Write and Seek
+		err     error      // error from a background flush
+		stats   FlushStats
+
+		stop     chan struct{} // closed to stop the background flusher
+		done     chan struct{} // closed when the background flusher returns
+		stopOnce sync.Once
+	}
+
+	// region is a dirty section of the file.
+	region struct {
+		off  int64
+		data []byte
+	}
+
+	// sizedInfo is file information with the size of
+	// the file including its dirty regions.
+	sizedInfo struct {
+		fs.FileInfo
+		size int64
+	}
+
+	// writeBackHandle implements Handle for a
+	// writeBackFile with its own position. Reads and
+	// writes go through the dirty regions, which
+	// already buffer writes.
+	//
+	// A writeBackHandle is not safe for concurrent use
+	// by multiple goroutines.
+	writeBackHandle struct {
+		f      *writeBackFile
+		off    int64
+		closed bool
+	}
+)
+
+// NewWriteBackFile returns a WriteBackFile for the named
+// file, which must already exist. Zero values in opts
+// are replaced with defaults.
+//
+// If there is an error, it will be of type *GoFileError.
+func NewWriteBackFile(name string, opts WriteBackOptions) (WriteBackFile, error) {
+	if opts.MaxDirty <= 0 {
+		opts.MaxDirty = defaultMaxDirty
+	}
+
+	f := &writeBackFile{maxDirty: opts.MaxDirty}
+	f.basicFile.init(name)
+
+	f.mu.Lock()
+	_, err := f.openLocked()
+	f.mu.Unlock()
+	if err != nil {
+		return nil, Err(err)
+	}
+
+	if opts.Interval > 0 {
+		f.stop = make(chan struct{})
+		f.done = make(chan struct{})
+		go f.flusher(opts.Interval)
+	}
+
+	return f, nil
+}
+
+// flusher writes dirty regions every interval until
+// f.stop is closed.
+func (f *writeBackFile) flusher(interval time.Duration) {
+	defer close(f.done)
+
+	t := time.NewTicker(interval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-f.stop:
+			return
+		case <-t.C:
+			f.wmu.Lock()
+			if len(f.regions) > 0 {
+				f.stats.ByTimer++
+				if err := f.writeRegions(); err != nil && f.err == nil {
+					f.err = err
+				}
+			}
+			f.wmu.Unlock()
+		}
+	}
+}
+
+// Stats returns a snapshot of the flush statistics.
+func (f *writeBackFile) Stats() FlushStats {
+	f.wmu.Lock()
+	defer f.wmu.Unlock()
+	return f.stats
+}
+
+// WriteAt records len(b) bytes to be written at byte
+// offset off. The data is copied; b may be reused as
+// soon as WriteAt returns.
+//
+// If the write takes the dirty data over MaxDirty, the
+// regions are flushed. The data is recorded even if
+// that flush fails, so WriteAt still returns len(b) and
+// nil. An error from a previous background or threshold
+// flush is returned, and cleared, by the next call.
+func (f *writeBackFile) WriteAt(b []byte, off int64) (n int, err error) {
+	if off < 0 {
+		return 0, NewGoFileError("gofile.WriteAt", f.providedName, ErrInvalid)
+	}
+
+	f.wmu.Lock()
+	defer f.wmu.Unlock()
+	return f.writeAt(b, off)
+}
+
+// Write records len(b) bytes to be written at the
+// current offset and advances the offset.
+func (f *writeBackFile) Write(b []byte) (n int, err error) {
+	f.wmu.Lock()
+	defer f.wmu.Unlock()
+
+	n, err = f.writeAt(b, f.off)
+	f.off += int64(n)
+	return n, err
+}
+
+// WriteString is Write for a string.
+func (f *writeBackFile) WriteString(s string) (n int, err error) {
+	return f.Write([]byte(s))
+}
+
+// ReadAt reads len(b) bytes starting at byte offset
+// off, including data that has not yet been flushed.
+func (f *writeBackFile) ReadAt(b []byte, off int64) (n int, err error) {
+	if off < 0 {
+		return 0, NewGoFileError("gofile.ReadAt", f.providedName, ErrInvalid)
+	}
+
+	f.wmu.Lock()
+	defer f.wmu.Unlock()
+	return f.readAt(b, off)
+}
+
+// Read reads up to len(b) bytes from the current
+// offset and advances the offset.
+func (f *writeBackFile) Read(b []byte) (n int, err error) {
+	f.wmu.Lock()
+	defer f.wmu.Unlock()
+
+	n, err = f.readAt(b, f.off)
+	f.off += int64(n)
+	if err == io.EOF && n > 0 {
+		err = nil
+	}
+	return n, err
+}
+
+// Seek sets the offset for the next Read or Write,
+// interpreted according to whence.
+func (f *writeBackFile) Seek(offset int64, whence int) (int64, error) {
+	f.wmu.Lock()
+	defer f.wmu.Unlock()
+
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += f.off
+	case io.SeekEnd:
+		size, err := f.sizeLocked()
+		if err != nil {
+			return 0, err
+		}
+		offset += size
+	default:
+		return 0, NewGoFileError("gofile.Seek", f.providedName, ErrInvalid)
+	}
+	if offset < 0 {
+		return 0, NewGoFileError("gofile.Seek", f.providedName, ErrInvalid)
+	}
+	f.off = offset
+	return offset, nil
+}
+
+// WriteTo writes the file from the current offset to
+// w, including data that has not yet been flushed, and
+// advances the offset.
+func (f *writeBackFile) WriteTo(w io.Writer) (n int64, err error) {
+	f.wmu.Lock()
+	defer f.wmu.Unlock()
+
+	buf := make([]byte, defaultBufSize)
+	for {
+		m, err := f.readAt(buf, f.off)
+		if m > 0 {
+			c, werr := w.Write(buf[:m])
+			n += int64(c)
+			f.off += int64(c)
+			if werr != nil {
+				return n, werr
+			}
+		}
+		if err == io.EOF {
+			return n, nil
+		}
+		if err != nil {
+			return n, err
+		}
+	}
+}
+
+// ReadFrom records the data read from r until io.EOF
+// to be written at the current offset, and advances
+// the offset.
+func (f *writeBackFile) ReadFrom(r io.Reader) (n int64, err error) {
+	f.wmu.Lock()
+	defer f.wmu.Unlock()
+
+	buf := make([]byte, defaultBufSize)
+	for {
+		m, rerr := r.Read(buf)
+		if m > 0 {
+			c, err := f.writeAt(buf[:m], f.off)
+			n += int64(c)
+			f.off += int64(c)
+			if err != nil {
+				return n, err
+			}
+		}
+		if rerr == io.EOF {
+			return n, nil
+		}
+		if rerr != nil {
+			return n, rerr
+		}
+	}
+}
+
+// Truncate changes the size of the file. Dirty data
+// beyond size is discarded.
+func (f *writeBackFile) Truncate(size int64) error {
+	if size < 0 {
+		return NewGoFileError("gofile.Truncate", f.providedName, ErrInvalid)
+	}
+
+	f.wmu.Lock()
+	defer f.wmu.Unlock()
+
+	regions := f.regions[:0]
+	for _, r := range f.regions {
+		switch {
+		case r.off >= size:
+			f.dirty -= int64(len(r.data))
+			continue
+		case r.end() > size:
+			f.dirty -= r.end() - size
+			r.data = r.data[:size-r.off]
+		}
+		regions = append(regions, r)
+	}
+	f.regions = regions
+	return f.basicFile.Truncate(size)
+}
+
+// Stat returns information about the file. Its size
+// includes dirty regions beyond the end of the file on
+// disk.
+func (f *writeBackFile) Stat() (fs.FileInfo, error) {
+	f.wmu.Lock()
+	defer f.wmu.Unlock()
+
+	size, err := f.sizeLocked()
+	if err != nil {
+		return nil, err
+	}
+	fi, err := f.basicFile.Stat()
+	if err != nil {
+		return nil, err
+	}
+	return sizedInfo{fi, size}, nil
+}
+
+// Info is an alias of Stat.
+func (f *writeBackFile) Info() (fs.FileInfo, error) { return f.Stat() }
+
+// FileInfo is Stat without the error, which is logged.
+func (f *writeBackFile) FileInfo() fs.FileInfo {
+	fi, err := f.Stat()
+	if Err(err) != nil {
+		return nil
+	}
+	return fi
+}
+
+// Size returns the size of the file, including dirty
+// regions. It is an alias of FileInfo().Size().
+func (f *writeBackFile) Size() int64 {
+	fi := f.FileInfo()
+	if fi == nil {
+		return 0
+	}
+	return fi.Size()
+}
+
+// Reader returns a buffered reader of the file that
+// sees the dirty regions.
+func (f *writeBackFile) Reader() io.Reader { return f.cfg.newReader(f) }
+
+// Writer returns a buffered writer to the file whose
+// writes are recorded as dirty regions.
+func (f *writeBackFile) Writer() io.Writer { return f.cfg.newWriter(f) }
+
+// Handle returns a Handle, positioned at the start of
+// the file, whose reads and writes go through the dirty
+// regions.
+func (f *writeBackFile) Handle() Handle {
+	return &writeBackHandle{f: f}
+}
+
+// Sync writes all dirty regions and commits the file
+// to stable storage.
+func (f *writeBackFile) Sync() error {
+	f.wmu.Lock()
+	defer f.wmu.Unlock()
+
+	if err := f.flushRegions(); err != nil {
+		return err
+	}
+
+	ff, err := f.rlockFile()
+	if err != nil {
+		return err
+	}
+	defer f.mu.RUnlock()
+	if err := ff.Sync(); err != nil {
+		return Err(NewGoFileError("gofile.Sync", f.providedName, err))
+	}
+	return nil
+}
+
+// Flush writes all dirty regions, commits the file to
+// stable storage and closes the underlying file.
+func (f *writeBackFile) Flush() error {
+	f.wmu.Lock()
+	defer f.wmu.Unlock()
+
+	if err := f.flushRegions(); err != nil {
+		return err
+	}
+	return f.basicFile.Flush()
+}
+
+// Close stops the background flusher, writes all
+// dirty regions and closes the underlying file. It is
+// safe to call more than once.
+func (f *writeBackFile) Close() error {
+	f.stopOnce.Do(func() {
+		if f.stop != nil {
+			close(f.stop)
+			<-f.done
+		}
+	})
+
+	f.wmu.Lock()
+	defer f.wmu.Unlock()
+
+	if err := f.flushRegions(); err != nil {
+		return err
+	}
+	return f.basicFile.Close()
+}
+
+// writeAt inserts a copy of b at off into the dirty
+// regions, merging it with any region it overlaps or
+// touches. The caller must hold f.wmu.
+func (f *writeBackFile) writeAt(b []byte, off int64) (int, error) {
+	if err := f.takeErr(); err != nil {
+		return 0, err
+	}
+	if len(b) == 0 {
+		return 0, nil
+	}
+	f.stats.Writes++
+
+	end := off + int64(len(b))
+
+	// regions[i:j] overlap or touch [off, end)
+	i := sort.Search(len(f.regions), func(k int) bool {
+		return f.regions[k].end() >= off
+	})
+	j := i
+	for j < len(f.regions) && f.regions[j].off <= end {
+		j++
+	}
+
+	if i == j {
+		r := region{off: off, data: append([]byte(nil), b...)}
+		f.regions = append(f.regions, region{})
+		copy(f.regions[i+1:], f.regions[i:])
+		f.regions[i] = r
+		f.dirty += int64(len(b))
+	} else {
+		f.stats.Coalesced++
+
+		start := off
+		if f.regions[i].off < start {
+			start = f.regions[i].off
+		}
+		if e := f.regions[j-1].end(); e > end {
+			end = e
+		}
+
+		data := make([]byte, end-start)
+		for _, r := range f.regions[i:j] {
+			copy(data[r.off-start:], r.data)
+			f.dirty -= int64(len(r.data))
+		}
+		copy(data[off-start:], b)
+
+		f.regions[i] = region{off: start, data: data}
+		f.regions = append(f.regions[:i+1], f.regions[j:]...)
+		f.dirty += int64(len(data))
+	}
+
+	if f.dirty >= f.maxDirty {
+		f.stats.ByThreshold++
+		if err := f.writeRegions(); err != nil && f.err == nil {
+			f.err = err
+		}
+	}
+	return len(b), nil
+}
+
+// readAt reads from the file and overlays the dirty
+// regions. The caller must hold f.wmu.
+func (f *writeBackFile) readAt(b []byte, off int64) (int, error) {
+	n, err := f.basicFile.ReadAt(b, off)
+	if err != nil && err != io.EOF {
+		return n, err
+	}
+
+	// dirty regions may extend the file
+	end := off + int64(n)
+	if k := len(f.regions); k > 0 {
+		if e := f.regions[k-1].end(); e > end {
+			end = e
+		}
+	}
+	if max := off + int64(len(b)); end > max {
+		end = max
+	}
+	if end <= off {
+		return 0, io.EOF
+	}
+
+	m := int(end - off)
+	for k := n; k < m; k++ {
+		b[k] = 0
+	}
+
+	for _, r := range f.regions {
+		if r.off >= end {
+			break
+		}
+		if r.end() <= off {
+			continue
+		}
+		src := r.data
+		dst := b[:m]
+		if r.off < off {
+			src = src[off-r.off:]
+		} else {
+			dst = dst[r.off-off:]
+		}
+		copy(dst, src)
+	}
+
+	if m < len(b) {
+		return m, io.EOF
+	}
+	return m, nil
+}
+
+// sizeLocked returns the size of the file including
+// dirty regions beyond its end. The caller must hold
+// f.wmu.
+func (f *writeBackFile) sizeLocked() (int64, error) {
+	f.basicFile.Dirty()
+	fi, err := f.basicFile.Stat()
+	if err != nil {
+		return 0, err
+	}
+	size := fi.Size()
+	if k := len(f.regions); k > 0 {
+		if e := f.regions[k-1].end(); e > size {
+			size = e
+		}
+	}
+	return size, nil
+}
+
+// flushRegions returns any pending background error,
+// then writes all dirty regions. The caller must hold
+// f.wmu.
+func (f *writeBackFile) flushRegions() error {
+	if err := f.takeErr(); err != nil {
+		return err
+	}
+	return f.writeRegions()
+}
+
+// writeRegions writes the dirty regions to the file in
+// file order and records the statistics. Regions that
+// could not be written are kept. The caller must hold
+// f.wmu.
+func (f *writeBackFile) writeRegions() error {
+	if len(f.regions) == 0 {
+		return nil
+	}
+
+	start := time.Now()
+	for len(f.regions) > 0 {
+		r := f.regions[0]
+		n, err := f.basicFile.WriteAt(r.data, r.off)
+		f.stats.Bytes += int64(n)
+		if err != nil {
+			return Err(NewGoFileError("gofile.writeRegions", f.providedName, err))
+		}
+		f.stats.Regions++
+		f.dirty -= int64(len(r.data))
+		f.regions = f.regions[1:]
+	}
+	f.regions = nil
+
+	f.stats.Flushes++
+	f.stats.LastFlush = time.Now()
+	f.stats.LastDuration = f.stats.LastFlush.Sub(start)
+	f.basicFile.Dirty()
+	return nil
+}
+
+// takeErr returns and clears the error from a
+// background flush. The caller must hold f.wmu.
+func (f *writeBackFile) takeErr() error {
+	err := f.err
+	f.err = nil
+	return err
+}
+
+func (r region) end() int64 { return r.off + int64(len(r.data)) }
+
+func (fi sizedInfo) Size() int64 { return fi.size }
+
+func (h *writeBackHandle) Read(p []byte) (int, error) {
+	if h.closed {
+		return 0, ErrClosed
+	}
+	n, err := h.f.ReadAt(p, h.off)
+	h.off += int64(n)
+	if err == io.EOF && n > 0 {
+		err = nil
+	}
+	return n, err
+}
+
+func (h *writeBackHandle) Write(p []byte) (int, error) {
+	if h.closed {
+		return 0, ErrClosed
+	}
+	n, err := h.f.WriteAt(p, h.off)
+	h.off += int64(n)
+	return n, err
+}
+
+func (h *writeBackHandle) WriteString(s string) (int, error) {
+	return h.Write([]byte(s))
+}
+
+// WriteTo writes the file from the position of the
+// handle to w.
+func (h *writeBackHandle) WriteTo(w io.Writer) (int64, error) {
+	return io.Copy(w, struct{ io.Reader }{h})
+}
+
+// ReadFrom writes the contents of r at the position of
+// the handle.
+func (h *writeBackHandle) ReadFrom(r io.Reader) (int64, error) {
+	return io.Copy(struct{ io.Writer }{h}, r)
+}
+
+// Close closes the handle. Dirty data stays with the
+// file until it is flushed.
+func (h *writeBackHandle) Close() error {
+	if h.closed {
+		return ErrClosed
+	}
+	h.closed = true
+	return nil
+}
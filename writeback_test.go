@@ -0,0 +1,194 @@
+package basicfile
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestWriteBackFile(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "wb.dat")
+	if err := os.WriteFile(name, []byte("0123456789"), NormalMode); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := NewWriteBackFile(name, WriteBackOptions{MaxDirty: 1 << 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	writes := []struct {
+		off  int64
+		data string
+	}{
+		{2, "ab"},
+		{4, "cd"},  // adjacent: coalesced
+		{3, "XY"},  // overlapping: coalesced
+		{12, "zz"}, // beyond end of file
+	}
+	for _, w := range writes {
+		if _, err := f.WriteAt([]byte(w.data), w.off); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	want := []byte("01aXYd6789\x00\x00zz")
+	got := make([]byte, len(want))
+	if _, err := f.ReadAt(got, 0); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("ReadAt() before flush = %q, want %q", got, want)
+	}
+
+	if err := f.Sync(); err != nil {
+		t.Fatal(err)
+	}
+	disk, _ := os.ReadFile(name)
+	if !bytes.Equal(disk, want) {
+		t.Errorf("file after Sync() = %q, want %q", disk, want)
+	}
+
+	st := f.Stats()
+	if st.Writes != 4 || st.Coalesced != 2 || st.Regions != 2 || st.Flushes != 1 {
+		t.Errorf("Stats() = %+v, want 4 writes, 2 coalesced, 2 regions, 1 flush", st)
+	}
+
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWriteBackFile_threshold(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "wb.dat")
+	if err := os.WriteFile(name, nil, NormalMode); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := NewWriteBackFile(name, WriteBackOptions{MaxDirty: 8})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	f.Write([]byte("1234"))
+	f.Write([]byte("5678"))
+	if st := f.Stats(); st.ByThreshold != 1 {
+		t.Errorf("ByThreshold = %d, want 1", st.ByThreshold)
+	}
+	if disk, _ := os.ReadFile(name); string(disk) != "12345678" {
+		t.Errorf("file after threshold flush = %q, want %q", disk, "12345678")
+	}
+}
+
+func TestWriteBackFile_streams(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "wb.dat")
+	if err := os.WriteFile(name, []byte("0123456789"), NormalMode); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := NewWriteBackFile(name, WriteBackOptions{Interval: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := f.WriteAt([]byte("XX"), 0); err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if _, err := io.Copy(&buf, f); err != nil || buf.String() != "XX23456789" {
+		t.Errorf("io.Copy from file = %q, %v, want %q", buf.String(), err, "XX23456789")
+	}
+
+	if _, err := f.Seek(4, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := io.Copy(f, struct{ io.Reader }{strings.NewReader("ab")}); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := os.ReadFile(name); string(got) != "0123456789" {
+		t.Errorf("io.Copy to file wrote %q to disk before a flush", got)
+	}
+
+	if _, err := f.WriteAt([]byte("zz"), 8); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Truncate(5); err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			f.Close()
+		}()
+	}
+	wg.Wait()
+
+	if got, _ := os.ReadFile(name); string(got) != "XX23a" {
+		t.Errorf("file = %q, want %q", got, "XX23a")
+	}
+}
+
+func TestWriteBackFile_accessors(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "wb.dat")
+	if err := os.WriteFile(name, []byte("0123456789"), NormalMode); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := NewWriteBackFile(name, WriteBackOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	if _, err := f.WriteAt([]byte("abcd"), 8); err != nil {
+		t.Fatal(err)
+	}
+	const want = "01234567abcd"
+
+	fi, err := f.Stat()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.Size() != int64(len(want)) {
+		t.Errorf("Stat().Size() = %d, want %d", fi.Size(), len(want))
+	}
+	if n, ok := sizeOf(f); !ok || n != int64(len(want)) {
+		t.Errorf("sizeOf() = %d, %v, want %d", n, ok, len(want))
+	}
+
+	wb := f.(*writeBackFile)
+	if got, err := io.ReadAll(wb.Reader()); err != nil || string(got) != want {
+		t.Errorf("Reader() read %q, %v, want %q", got, err, want)
+	}
+
+	h := wb.Handle()
+	if _, err := h.WriteString("XY"); err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if _, err := h.WriteTo(&buf); err != nil || buf.String() != want[2:] {
+		t.Errorf("Handle().WriteTo() = %q, %v, want %q", buf.String(), err, want[2:])
+	}
+	if err := h.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if disk, _ := os.ReadFile(name); string(disk) != "0123456789" {
+		t.Errorf("Handle() wrote %q to disk before a flush", disk)
+	}
+
+	if err := f.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if disk, _ := os.ReadFile(name); string(disk) != "XY234567abcd" {
+		t.Errorf("file after Flush() = %q, want %q", disk, "XY234567abcd")
+	}
+}